@@ -0,0 +1,67 @@
+package goftp
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+//EPRT命令中表示地址类型的数字，定义见RFC 2428
+const (
+	EPRT_NET_PRT_IPV4 = 1
+	EPRT_NET_PRT_IPV6 = 2
+)
+
+//主动模式下在控制连接的本地地址上监听一个端口，然后通过PORT或者
+//EPRT命令把这个地址告诉服务器，IPv4地址使用PORT，IPv6地址使用EPRT
+func (this *Client) listenActive() (listener net.Listener, err error) {
+	if this.conn == nil {
+		return nil, ErrNotConnected
+	}
+	var localIP = this.conn.LocalAddr().(*net.TCPAddr).IP
+	listener, err = net.Listen("tcp", net.JoinHostPort(localIP.String(), "0"))
+	if err != nil {
+		return
+	}
+	var port = listener.Addr().(*net.TCPAddr).Port
+	if ipv4 := localIP.To4(); ipv4 != nil {
+		//PORT h1,h2,h3,h4,p1,p2
+		var portParam = fmt.Sprintf("%d,%d,%d,%d,%d,%d", ipv4[0], ipv4[1], ipv4[2], ipv4[3], port/256, port%256)
+		_, err = this.cmdOK(FC_PORT, portParam)
+	} else {
+		//EPRT |2|addr|port|
+		var eprtParam = fmt.Sprintf("|%d|%s|%d|", EPRT_NET_PRT_IPV6, localIP.String(), port)
+		_, err = this.cmdOK(FC_EPRT, eprtParam)
+	}
+	if err != nil {
+		listener.Close()
+		listener = nil
+	}
+	return
+}
+
+//等待服务器连接到主动模式监听的端口，连接过来的地址必须和控制连接的
+//服务器地址相同，防止其他主机抢先连接进来
+func (this *Client) acceptActive(listener net.Listener) (dataConn net.Conn, err error) {
+	listener.(*net.TCPListener).SetDeadline(time.Now().Add(this.timeout))
+	dataConn, err = listener.Accept()
+	if err != nil {
+		return
+	}
+	if this.opts.SkipActivePeerCheck {
+		return
+	}
+	serverIP, err := this.remoteIP()
+	if err != nil {
+		dataConn.Close()
+		dataConn = nil
+		return
+	}
+	var peerIP = dataConn.RemoteAddr().(*net.TCPAddr).IP
+	if !peerIP.Equal(serverIP) {
+		dataConn.Close()
+		dataConn = nil
+		err = fmt.Errorf("Data connection from %s does not match server address %s", peerIP, serverIP)
+	}
+	return
+}
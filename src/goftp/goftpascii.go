@@ -0,0 +1,102 @@
+package goftp
+
+import (
+	"io"
+	"runtime"
+)
+
+//本地文本文件使用的换行
+var LOCAL_NEWLINE = localNewline()
+
+func localNewline() string {
+	if runtime.GOOS == "windows" {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+//文本模式下载时使用的Writer，把网络上CRLF形式的换行转换为本地的换行。
+//CR和LF可能分别出现在两次写入的末尾和开头，所以末尾的CR要等到下一次
+//写入的时候才能确定如何处理，全部写完之后需要调用Flush
+type asciiWriter struct {
+	writer    io.Writer
+	newline   []byte //本地的换行
+	pendingCR bool   //上一次写入的末尾是不是CR
+	buf       []byte
+}
+
+func newASCIIWriter(writer io.Writer, newline string) *asciiWriter {
+	return &asciiWriter{writer: writer, newline: []byte(newline)}
+}
+
+func (this *asciiWriter) Write(data []byte) (n int, err error) {
+	var buf = this.buf[:0]
+	for _, b := range data {
+		if this.pendingCR {
+			this.pendingCR = false
+			if b == '\n' {
+				buf = append(buf, this.newline...)
+				continue
+			}
+			//单独的CR原样保留
+			buf = append(buf, '\r')
+		}
+		if b == '\r' {
+			this.pendingCR = true
+		} else {
+			buf = append(buf, b)
+		}
+	}
+	this.buf = buf
+	if _, err = this.writer.Write(buf); err != nil {
+		return
+	}
+	n = len(data)
+	return
+}
+
+//写入最后还没有处理的CR
+func (this *asciiWriter) Flush() (err error) {
+	if this.pendingCR {
+		this.pendingCR = false
+		_, err = this.writer.Write([]byte{'\r'})
+	}
+	return
+}
+
+//文本模式上传时使用的Reader，把本地的换行转换为网络上CRLF形式的换行。
+//LF前面已经有CR的时候不再添加，这样Windows下面的CRLF也可以原样发送，
+//上一次读取的末尾是不是CR需要记录下来
+type asciiReader struct {
+	reader  io.Reader
+	lastCR  bool   //上一个读到的字节是不是CR
+	raw     []byte //从reader中读取的原始数据
+	pending []byte //已经转换还没有返回的数据
+	err     error  //reader返回的错误，等转换好的数据都返回之后再返回
+}
+
+func newASCIIReader(reader io.Reader) *asciiReader {
+	return &asciiReader{reader: reader, raw: make([]byte, 32*1024)}
+}
+
+func (this *asciiReader) Read(data []byte) (n int, err error) {
+	for len(this.pending) == 0 {
+		if this.err != nil {
+			return 0, this.err
+		}
+		var readCount int
+		readCount, this.err = this.reader.Read(this.raw)
+		var pending = this.pending[:0]
+		for _, b := range this.raw[:readCount] {
+			if b == '\n' && !this.lastCR {
+				pending = append(pending, '\r')
+			}
+			pending = append(pending, b)
+			this.lastCR = b == '\r'
+		}
+		this.pending = pending
+	}
+	n = copy(data, this.pending)
+	this.pending = this.pending[n:]
+	return
+}
@@ -0,0 +1,91 @@
+package goftp
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestASCIIWriter(t *testing.T) {
+	var tests = []struct {
+		name    string
+		chunks  []string
+		newline string
+		want    string
+	}{
+		{"crlf", []string{"a\r\nb\r\n"}, "\n", "a\nb\n"},
+		{"crlf to windows newline", []string{"a\r\nb"}, "\r\n", "a\r\nb"},
+		//CR在一次写入的末尾，LF在下一次写入的开头
+		{"split crlf", []string{"a\r", "\nb"}, "\n", "a\nb"},
+		{"split crlf with empty write", []string{"a\r", "", "\nb"}, "\n", "a\nb"},
+		{"lone cr", []string{"a\rb"}, "\n", "a\rb"},
+		{"lone cr at end of write", []string{"a\r", "b"}, "\n", "a\rb"},
+		{"trailing cr flushed", []string{"a\r"}, "\n", "a\r"},
+		{"cr cr lf", []string{"a\r\r\nb"}, "\n", "a\r\nb"},
+		{"split cr cr lf", []string{"a\r", "\r", "\n"}, "\n", "a\r\n"},
+		{"bare lf kept", []string{"a\nb"}, "\n", "a\nb"},
+		{"empty", []string{""}, "\n", ""},
+	}
+	for _, test := range tests {
+		var out bytes.Buffer
+		var writer = newASCIIWriter(&out, test.newline)
+		for _, chunk := range test.chunks {
+			n, err := writer.Write([]byte(chunk))
+			if err != nil || n != len(chunk) {
+				t.Fatalf("%s: Write(%q) = %d, %v", test.name, chunk, n, err)
+			}
+		}
+		if err := writer.Flush(); err != nil {
+			t.Fatalf("%s: Flush() error = %v", test.name, err)
+		}
+		if got := out.String(); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestASCIIReader(t *testing.T) {
+	var tests = []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"lf", "a\nb\n", "a\r\nb\r\n"},
+		{"crlf kept", "a\r\nb", "a\r\nb"},
+		{"lone cr", "a\rb", "a\rb"},
+		{"leading lf", "\nb", "\r\nb"},
+		{"cr cr lf", "a\r\r\n", "a\r\r\n"},
+		{"lf lf", "\n\n", "\r\n\r\n"},
+		{"empty", "", ""},
+	}
+	for _, test := range tests {
+		//每次只从文件读一个字节，CR和LF一定在两次读取中
+		var splitInput = newASCIIReader(iotest.OneByteReader(strings.NewReader(test.input)))
+		//每次只读一个字节，转换好的数据要分多次返回
+		var splitOutput = iotest.OneByteReader(newASCIIReader(strings.NewReader(test.input)))
+		for name, reader := range map[string]io.Reader{"split input": splitInput, "split output": splitOutput} {
+			got, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("%s (%s): ReadAll() error = %v", test.name, name, err)
+			}
+			if string(got) != test.want {
+				t.Errorf("%s (%s): got %q, want %q", test.name, name, got, test.want)
+			}
+		}
+	}
+}
+
+func TestASCIIReaderError(t *testing.T) {
+	//reader的错误要等转换好的数据都返回之后再返回
+	var reader = newASCIIReader(iotest.DataErrReader(iotest.TimeoutReader(strings.NewReader("a\nb\n"))))
+	var data = make([]byte, 64)
+	n, err := reader.Read(data)
+	if err != nil || string(data[:n]) != "a\r\nb\r\n" {
+		t.Fatalf("Read() = %q, %v", data[:n], err)
+	}
+	if _, err = reader.Read(data); err != iotest.ErrTimeout {
+		t.Errorf("Read() error = %v, want %v", err, iotest.ErrTimeout)
+	}
+}
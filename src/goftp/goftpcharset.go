@@ -0,0 +1,334 @@
+package goftp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+//控制连接上使用的字符集，发送的命令(包括路径)用Encode从UTF-8转换为
+//服务器的编码，收到的回复和文件列表用Decode转换为UTF-8。其他编码可以
+//实现这个接口后通过RegisterCharset注册，比如用golang.org/x/text
+type Charset interface {
+	Name() string                    //字符集的名字
+	Encode(s string) (string, error) //把UTF-8字符串转换为这个字符集的编码
+	Decode(s string) (string, error) //把这个字符集编码的字符串转换为UTF-8
+}
+
+var (
+	charsetsMutex sync.RWMutex
+	charsets      = make(map[string]Charset)
+)
+
+func init() {
+	RegisterCharset(utf8Charset{}, "utf8")
+	RegisterCharset(latin1Charset{}, "latin1", "latin-1", "iso8859-1")
+	RegisterCharset(&dbcsCharset{name: "gbk", table: &gb18030Table}, "cp936")
+	RegisterCharset(&dbcsCharset{name: "gb18030", table: &gb18030Table, fourByte: true})
+	RegisterCharset(&dbcsCharset{name: "big5", table: &big5Table}, "cp950")
+	RegisterCharset(&dbcsCharset{name: "shift_jis", table: &shiftJISTable, katakana: true},
+		"sjis", "cp932", "windows-31j")
+}
+
+//注册一个字符集，名字和别名都不区分大小写
+func RegisterCharset(charset Charset, aliases ...string) {
+	charsetsMutex.Lock()
+	defer charsetsMutex.Unlock()
+	for _, name := range append([]string{charset.Name()}, aliases...) {
+		charsets[strings.ToLower(name)] = charset
+	}
+}
+
+//根据名字或者别名查找字符集
+func LookupCharset(name string) (charset Charset, err error) {
+	charsetsMutex.RLock()
+	defer charsetsMutex.RUnlock()
+	charset, ok := charsets[strings.ToLower(name)]
+	if !ok {
+		err = fmt.Errorf("Unknown charset `%s'", name)
+	}
+	return
+}
+
+//所有注册的字符集的名字，不包括别名
+func CharsetNames() (names []string) {
+	charsetsMutex.RLock()
+	defer charsetsMutex.RUnlock()
+	for name, charset := range charsets {
+		if name == strings.ToLower(charset.Name()) {
+			names = append(names, charset.Name())
+		}
+	}
+	sort.Strings(names)
+	return
+}
+
+//UTF-8，字符串原样发送和接收
+type utf8Charset struct{}
+
+func (this utf8Charset) Name() string {
+	return "utf-8"
+}
+
+func (this utf8Charset) Encode(s string) (string, error) {
+	return s, nil
+}
+
+func (this utf8Charset) Decode(s string) (string, error) {
+	return s, nil
+}
+
+//ISO-8859-1，每个字节对应一个U+0000到U+00FF的字符
+type latin1Charset struct{}
+
+func (this latin1Charset) Name() string {
+	return "iso-8859-1"
+}
+
+func (this latin1Charset) Encode(s string) (encoded string, err error) {
+	var buf = make([]byte, 0, len(s))
+	for _, r := range s {
+		if r > 0xFF {
+			return "", newEncodeError(r, this.Name())
+		}
+		buf = append(buf, byte(r))
+	}
+	return string(buf), nil
+}
+
+func (this latin1Charset) Decode(s string) (decoded string, err error) {
+	var builder strings.Builder
+	for i := 0; i < len(s); i++ {
+		builder.WriteRune(rune(s[i]))
+	}
+	return builder.String(), nil
+}
+
+//字符不能用某个字符集编码
+func newEncodeError(r rune, charsetName string) error {
+	return fmt.Errorf("Character %q can't be encoded in %s", r, charsetName)
+}
+
+//双字节字符集的码表，见goftpcharsettable.go
+type dbcsTable struct {
+	lead      byte            //第一个前导字节
+	rows      []string        //每个前导字节对应的字符
+	preferred map[rune]uint16 //同一个字符有多个编码，并且不是使用第一个编码的时候，编码时使用的编码
+}
+
+//GB18030四字节编码中的一段连续的编码
+type gb18030Range struct {
+	linear int  //第一个四字节编码的线性序号
+	first  rune //对应的第一个字符
+	count  int  //这一段的长度
+}
+
+//第二个字节的范围
+const (
+	DBCS_TRAIL_MIN = 0x40
+	DBCS_TRAIL_MAX = 0xFE
+)
+
+//GB18030中U+10000对应的四字节编码0x90308130的线性序号
+const GB18030_SUPPLEMENTARY_LINEAR = 189000
+
+//Shift_JIS中单字节的半角片假名，0xA1到0xDF对应U+FF61到U+FF9F
+const (
+	SJIS_KATAKANA_MIN   = 0xA1
+	SJIS_KATAKANA_MAX   = 0xDF
+	SJIS_KATAKANA_FIRST = 0xFF61
+)
+
+//GBK、GB18030、Big5和Shift_JIS这类ASCII兼容的双字节字符集，码表在第一次
+//使用的时候才展开
+type dbcsCharset struct {
+	name     string
+	table    *dbcsTable
+	fourByte bool //GB18030的四字节编码
+	katakana bool //Shift_JIS的单字节半角片假名
+
+	once    sync.Once
+	decoder [][]rune        //前导字节和第二个字节对应的字符
+	encoder map[rune]uint16 //字符对应的双字节编码
+}
+
+func (this *dbcsCharset) Name() string {
+	return this.name
+}
+
+func (this *dbcsCharset) init() {
+	this.decoder = make([][]rune, len(this.table.rows))
+	this.encoder = make(map[rune]uint16)
+	for i, row := range this.table.rows {
+		if row == "" {
+			continue
+		}
+		this.decoder[i] = []rune(row)
+		for j, r := range this.decoder[i] {
+			if r == utf8.RuneError {
+				continue
+			}
+			var code = uint16(int(this.table.lead)+i)<<8 | uint16(DBCS_TRAIL_MIN+j)
+			//同一个字符有多个编码的时候使用第一个
+			if _, ok := this.encoder[r]; !ok {
+				this.encoder[r] = code
+			}
+		}
+	}
+	for r, code := range this.table.preferred {
+		this.encoder[r] = code
+	}
+}
+
+//查找双字节编码对应的字符
+func (this *dbcsCharset) decodePair(lead byte, trail byte) (r rune, ok bool) {
+	if lead < this.table.lead || int(lead-this.table.lead) >= len(this.decoder) ||
+		trail < DBCS_TRAIL_MIN || trail > DBCS_TRAIL_MAX {
+		return
+	}
+	var row = this.decoder[lead-this.table.lead]
+	if row == nil {
+		return
+	}
+	r = row[trail-DBCS_TRAIL_MIN]
+	return r, r != utf8.RuneError
+}
+
+func (this *dbcsCharset) Decode(s string) (decoded string, err error) {
+	this.once.Do(this.init)
+	var builder strings.Builder
+	for i := 0; i < len(s); {
+		var c = s[i]
+		if c < utf8.RuneSelf {
+			builder.WriteByte(c)
+			i++
+			continue
+		}
+		if this.katakana && c >= SJIS_KATAKANA_MIN && c <= SJIS_KATAKANA_MAX {
+			builder.WriteRune(SJIS_KATAKANA_FIRST + rune(c-SJIS_KATAKANA_MIN))
+			i++
+			continue
+		}
+		if this.fourByte && i+3 < len(s) {
+			if r, ok := decodeGB18030FourByte(s[i : i+4]); ok {
+				builder.WriteRune(r)
+				i += 4
+				continue
+			}
+		}
+		if i+1 < len(s) {
+			if r, ok := this.decodePair(c, s[i+1]); ok {
+				builder.WriteRune(r)
+				i += 2
+				continue
+			}
+		}
+		//不认识的字节替换为U+FFFD，回复和文件列表总要能显示出来
+		builder.WriteRune(utf8.RuneError)
+		i++
+	}
+	return builder.String(), nil
+}
+
+func (this *dbcsCharset) Encode(s string) (encoded string, err error) {
+	this.once.Do(this.init)
+	var buf = make([]byte, 0, len(s))
+	for i, r := range s {
+		switch {
+		case r < utf8.RuneSelf:
+			buf = append(buf, byte(r))
+		case r == utf8.RuneError && !strings.HasPrefix(s[i:], string(utf8.RuneError)):
+			return "", fmt.Errorf("Invalid UTF-8 string %q", s)
+		case this.katakana && r >= SJIS_KATAKANA_FIRST && r <= SJIS_KATAKANA_FIRST+SJIS_KATAKANA_MAX-SJIS_KATAKANA_MIN:
+			buf = append(buf, byte(r-SJIS_KATAKANA_FIRST)+SJIS_KATAKANA_MIN)
+		default:
+			if code, ok := this.encoder[r]; ok {
+				buf = append(buf, byte(code>>8), byte(code))
+			} else if fourBytes, ok := this.encodeFourByte(r); ok {
+				buf = append(buf, fourBytes...)
+			} else {
+				return "", newEncodeError(r, this.name)
+			}
+		}
+	}
+	return string(buf), nil
+}
+
+//只有GB18030有四字节编码
+func (this *dbcsCharset) encodeFourByte(r rune) (encoded []byte, ok bool) {
+	if !this.fourByte {
+		return
+	}
+	return encodeGB18030FourByte(r)
+}
+
+//解析GB18030的四字节编码，第一和第三个字节是0x81到0xFE，第二和第四个
+//字节是0x30到0x39
+func decodeGB18030FourByte(s string) (r rune, ok bool) {
+	if s[0] < 0x81 || s[0] > 0xFE || s[1] < 0x30 || s[1] > 0x39 ||
+		s[2] < 0x81 || s[2] > 0xFE || s[3] < 0x30 || s[3] > 0x39 {
+		return
+	}
+	var linear = (((int(s[0])-0x81)*10+int(s[1])-0x30)*126+int(s[2])-0x81)*10 + int(s[3]) - 0x30
+	if linear >= GB18030_SUPPLEMENTARY_LINEAR {
+		r = rune(0x10000 + linear - GB18030_SUPPLEMENTARY_LINEAR)
+		return r, r <= utf8.MaxRune
+	}
+	var index = sort.Search(len(gb18030Ranges), func(i int) bool {
+		return gb18030Ranges[i].linear > linear
+	}) - 1
+	if index < 0 || linear >= gb18030Ranges[index].linear+gb18030Ranges[index].count {
+		return
+	}
+	return gb18030Ranges[index].first + rune(linear-gb18030Ranges[index].linear), true
+}
+
+//把字符编码为GB18030的四字节编码，双字节编码的字符要先在码表中查找
+func encodeGB18030FourByte(r rune) (encoded []byte, ok bool) {
+	var linear int
+	if r >= 0x10000 {
+		if r > utf8.MaxRune {
+			return
+		}
+		linear = int(r-0x10000) + GB18030_SUPPLEMENTARY_LINEAR
+	} else {
+		var index = sort.Search(len(gb18030Ranges), func(i int) bool {
+			return gb18030Ranges[i].first > r
+		}) - 1
+		if index < 0 || int(r-gb18030Ranges[index].first) >= gb18030Ranges[index].count {
+			return
+		}
+		linear = gb18030Ranges[index].linear + int(r-gb18030Ranges[index].first)
+	}
+	encoded = []byte{
+		byte(linear/12600 + 0x81),
+		byte(linear/1260%10 + 0x30),
+		byte(linear/10%126 + 0x81),
+		byte(linear%10 + 0x30),
+	}
+	return encoded, true
+}
+
+//设置控制连接使用的字符集，为nil时不转换
+func (this *Client) SetCharset(charset Charset) {
+	this.charset = charset
+}
+
+//控制连接使用的字符集，没有设置的时候为nil
+func (this *Client) Charset() Charset {
+	return this.charset
+}
+
+//把服务器发送的文本转换为UTF-8，内置的字符集解码时不会出错，其他字符集
+//出错的时候保留原始内容
+func (this *Client) decode(s string) string {
+	if this.charset == nil {
+		return s
+	}
+	if decoded, err := this.charset.Decode(s); err == nil {
+		return decoded
+	}
+	return s
+}
@@ -0,0 +1,113 @@
+package goftp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCharsetEncodeDecode(t *testing.T) {
+	var tests = []struct {
+		charset string
+		utf8    string
+		encoded string
+	}{
+		{"utf-8", "中文/文件.txt", "中文/文件.txt"},
+		{"latin1", "café", "caf\xe9"},
+		{"gbk", "中文目录/文件.txt", "\xd6\xd0\xce\xc4\xc4\xbf\xc2\xbc/\xce\xc4\xbc\xfe.txt"},
+		{"cp936", "中文", "\xd6\xd0\xce\xc4"},
+		{"gb18030", "中文", "\xd6\xd0\xce\xc4"},
+		{"gb18030", "€", "\xa2\xe3"},
+		//GBK中没有的字符使用GB18030的四字节编码
+		{"gb18030", "ß", "\x81\x30\x89\x38"},
+		{"gb18030", "😀", "\x94\x39\xfc\x36"},
+		{"big5", "中文檔案", "\xa4\xa4\xa4\xe5\xc0\xc9\xae\xd7"},
+		{"shift_jis", "日本語", "\x93\xfa\x96\x7b\x8c\xea"},
+		//半角片假名是单字节的
+		{"sjis", "ｱｲ.txt", "\xb1\xb2.txt"},
+		{"gbk", "plain ascii", "plain ascii"},
+		{"gbk", "", ""},
+	}
+	for _, test := range tests {
+		charset, err := LookupCharset(test.charset)
+		if err != nil {
+			t.Fatalf("LookupCharset(%q) error = %v", test.charset, err)
+		}
+		encoded, err := charset.Encode(test.utf8)
+		if err != nil || encoded != test.encoded {
+			t.Errorf("%s: Encode(%q) = %q, %v, want %q", test.charset, test.utf8, encoded, err, test.encoded)
+		}
+		decoded, err := charset.Decode(test.encoded)
+		if err != nil || decoded != test.utf8 {
+			t.Errorf("%s: Decode(%q) = %q, %v, want %q", test.charset, test.encoded, decoded, err, test.utf8)
+		}
+	}
+}
+
+func TestCharsetEncodeError(t *testing.T) {
+	var tests = []struct {
+		charset string
+		utf8    string
+	}{
+		{"latin1", "中"},
+		{"gbk", "ß"},
+		{"gbk", "😀"},
+		{"big5", "😀"},
+		{"shift_jis", "中文ß"},
+		{"gbk", "bad \xff utf-8"},
+	}
+	for _, test := range tests {
+		charset, _ := LookupCharset(test.charset)
+		if encoded, err := charset.Encode(test.utf8); err == nil {
+			t.Errorf("%s: Encode(%q) = %q, want error", test.charset, test.utf8, encoded)
+		}
+	}
+}
+
+func TestCharsetDecodeInvalid(t *testing.T) {
+	//不认识的字节替换为U+FFFD，后面的内容照常解码
+	var tests = []struct {
+		charset string
+		encoded string
+		want    string
+	}{
+		{"gbk", "\xd6", "�"},
+		{"gbk", "a\xd6", "a�"},
+		{"gbk", "\xd6 ", "� "},
+		{"gbk", "\xff\xff\xd6\xd0", "��中"},
+		{"gb18030", "\x81\x30\x89", "�0�"},
+		{"big5", "\x80\xa4\xa4", "�中"},
+	}
+	for _, test := range tests {
+		charset, _ := LookupCharset(test.charset)
+		decoded, err := charset.Decode(test.encoded)
+		if err != nil || decoded != test.want {
+			t.Errorf("%s: Decode(%q) = %q, %v, want %q", test.charset, test.encoded, decoded, err, test.want)
+		}
+	}
+}
+
+func TestLookupCharset(t *testing.T) {
+	for alias, name := range map[string]string{
+		"UTF-8":       "utf-8",
+		"utf8":        "utf-8",
+		"ISO-8859-1":  "iso-8859-1",
+		"Latin1":      "iso-8859-1",
+		"GBK":         "gbk",
+		"CP936":       "gbk",
+		"GB18030":     "gb18030",
+		"cp950":       "big5",
+		"Windows-31J": "shift_jis",
+	} {
+		charset, err := LookupCharset(alias)
+		if err != nil || charset.Name() != name {
+			t.Errorf("LookupCharset(%q) = %v, %v, want %s", alias, charset, err, name)
+		}
+	}
+	if _, err := LookupCharset("ebcdic"); err == nil {
+		t.Errorf("LookupCharset(\"ebcdic\") error = nil, want error")
+	}
+	var want = []string{"big5", "gb18030", "gbk", "iso-8859-1", "shift_jis", "utf-8"}
+	if names := CharsetNames(); !reflect.DeepEqual(names, want) {
+		t.Errorf("CharsetNames() = %q, want %q", names, want)
+	}
+}
@@ -4,12 +4,9 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
-	"net"
+	"io"
 	"os"
-	"os/user"
-	"strconv"
 	"strings"
-	"time"
 )
 
 //定义ftp客户端支持的用户命令，FCC表示Ftp Client Command
@@ -29,6 +26,47 @@ const (
 	FCC_LCD           string = "lcd"
 	FCC_OPEN          string = "open"
 	FCC_USER          string = "user"
+	FCC_GET           string = "get"
+	FCC_RECV          string = "recv"
+	FCC_PUT           string = "put"
+	FCC_SEND          string = "send"
+	FCC_APPEND        string = "append"
+	FCC_SUNIQUE       string = "sunique"
+	FCC_ASCII         string = "ascii"
+	FCC_BINARY        string = "binary"
+	FCC_IMAGE         string = "image"
+	FCC_TYPE          string = "type"
+	FCC_PASSIVE       string = "passive"
+	FCC_EPSV4         string = "epsv4"
+	FCC_SSL           string = "ssl"
+	FCC_PROT          string = "prot"
+	FCC_MLSD          string = "mlsd"
+	FCC_MLST          string = "mlst"
+	FCC_FEATURES      string = "features"
+	FCC_CHARSET       string = "charset"
+	FCC_REGET         string = "reget"
+	FCC_REPUT         string = "reput"
+	FCC_RESTART       string = "restart"
+	FCC_MGET          string = "mget"
+	FCC_MPUT          string = "mput"
+	FCC_MDELETE       string = "mdelete"
+	FCC_MDIR          string = "mdir"
+	FCC_GLOB          string = "glob"
+	FCC_PROMPT        string = "prompt"
+	FCC_MIRROR        string = "mirror"
+	FCC_CDUP          string = "cdup"
+	FCC_MKDIR         string = "mkdir"
+	FCC_RMDIR         string = "rmdir"
+	FCC_DELETE        string = "delete"
+	FCC_RENAME        string = "rename"
+	FCC_CHMOD         string = "chmod"
+	FCC_SIZE          string = "size"
+	FCC_MODTIME       string = "modtime"
+	FCC_LITERAL       string = "literal"
+	FCC_QUOTE         string = "quote"
+	FCC_SITE          string = "site"
+	FCC_HASH          string = "hash"
+	FCC_PROGRESS      string = "progress"
 
 	//这个命令是为了方便学习ftp客户端而加上的，并不是ftp客户端的标准命令
 	FCC_USAGE string = "usage"
@@ -41,7 +79,19 @@ const (
 //表示ftp客户端的结构体
 type GoFtpClient struct {
 	Host string //ftp服务器主机名
-	Port int    //ftp服务器监听端口号
+	Port int    //ftp服务器监听端口号，为0时使用默认端口号
+
+	ImplicitTLS bool //使用隐式FTPS连接ftp服务器
+
+	CAFile         string //验证服务器证书的CA证书文件，为空时使用系统的CA证书
+	CertFile       string //客户端证书文件
+	KeyFile        string //客户端证书的私钥文件，为空时从CertFile中读取
+	KnownHostsFile string //记录服务器证书指纹的文件，为空时不记录
+
+	Charset Charset //控制连接使用的字符集，为nil时不转换
+
+	NoGlob   bool //多个文件的命令不展开通配符，可以用glob命令切换
+	NoPrompt bool //多个文件的命令不对每个文件询问，可以用prompt命令切换
 
 	running      bool           //表示ftp客户端是否处于运行中的flag
 	ftpClientCmd GoFtpClientCmd //组合的ftp客户端命令结构体
@@ -52,81 +102,91 @@ type GoFtpClient struct {
 //使用初始命令行参数来连接ftp服务器
 func (this *GoFtpClient) TryConnect() {
 	this.running = true
+	this.initCmd()
+	printCmdError(this.ftpClientCmd.connect(this.Host, this.Port, this.ImplicitTLS))
+	//不管是否连接ftp服务器成功，我们都会进入命令交互模式
+	this.EnterPromptMode()
+}
 
-	//根据提供的主机名解析对应的ip地址，一个主机名可能有多个ip地址，
-	//尝试依次进行连接，连接成功就不再尝试下一个ip地址
-	ips, lookupErr := net.LookupIP(this.Host)
-	if lookupErr != nil {
-		fmt.Println("ftp: Can't lookup host `", this.Host, "'")
-	} else {
-		var port = strconv.Itoa(this.Port)
-		for _, ip := range ips {
-			//尝试连接ftp服务器，并设置连接的等待超时时间
-			conn, connErr := net.DialTimeout("tcp", net.JoinHostPort(ip.String(), port),
-				time.Duration(DIAL_FTP_SERVER_TIMEOUT_SECONDS)*time.Second)
-			if connErr != nil {
-				//连接出错了，悲剧，打印错误信息，然后尝试下一个ip地址
-				fmt.Println("Trying ", ip, "...")
-				fmt.Println("ftp:", connErr.Error())
-			} else {
-				fmt.Println("Connected to", ip, ".")
-				//获取操作系统当前登录用户
-				var sysUser, _ = user.Current()
-				//设置ftp客户端命令结构体对象信息
-				this.ftpClientCmd = GoFtpClientCmd{
-					FtpConn:             conn,
-					Connected:           true,
-					Username:            sysUser.Username,
-					DefaultLocalWorkDir: sysUser.HomeDir,
-					LocalWorkDir:        sysUser.HomeDir,
-				}
-				//打印ftp服务器连接回复信息，并提示用户登录
-				this.ftpClientCmd.welcome()
-				//既然已经找到了能够连接的ip地址，下面的即使有也不去尝试了
-				break
-			}
-		}
-		//不管是否连接ftp服务器成功，我们都会进入命令交互模式
-		this.EnterPromptMode()
-	}
+//把命令行上设置的证书和字符集选项传给ftp客户端命令
+func (this *GoFtpClient) initCmd() {
+	this.ftpClientCmd.CAFile = this.CAFile
+	this.ftpClientCmd.CertFile = this.CertFile
+	this.ftpClientCmd.KeyFile = this.KeyFile
+	this.ftpClientCmd.KnownHostsFile = this.KnownHostsFile
+	this.ftpClientCmd.Charset = this.Charset
+	this.ftpClientCmd.NoGlob = this.NoGlob
+	this.ftpClientCmd.NoPrompt = this.NoPrompt
+}
+
+//标准输入是否为终端，从管道或者文件读取命令的时候是批处理模式，
+//这时候不能询问用户
+func isInteractive() bool {
+	stat, err := os.Stdin.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
+
+//所有的交互输入共用一个带缓冲的读取器，如果每次都新建一个，
+//前一个读取器里面缓冲的还没有处理的输入就丢失了
+var stdinReader = bufio.NewReader(os.Stdin)
+
+//打印提示信息，然后读取用户输入的一行
+func readInput(prompt string) (input string, err error) {
+	fmt.Print(prompt)
+	input, err = stdinReader.ReadString('\n')
+	//这里把读取的数据后面的换行去掉，对于Mac是"\r"，Linux下面
+	//是"\n"，Windows下面是"\r\n"，所以为了支持多平台，直接用
+	//"\r\n"作为过滤字符
+	input = strings.Trim(input, "\r\n")
+	return
 }
 
 //进入命令交互模式
 func (this *GoFtpClient) EnterPromptMode() {
 	//设置ftp客户端运行状态
 	this.running = true
+	this.initCmd()
 	//在ftp客户端运行状态为true的时候，不断地检测用户输入的交互命令
 	//然后解析输入的命令，并执行解析后的命令，执行完，再次等待用户
 	//的交互命令
 	for this.running {
-		fmt.Print("ftp>")
-
 		//这里使用bufio来读取用户的一行交互输入，这里之所以不使用
 		//fmt包里面的scan那些函数，是因为用户的交互输入格式为命令
 		//然后可能跟上一些参数，中间用空格分开。scan函数没有办法
 		//一次读取这些数据，因为scan函数遇到空格就停止了，把剩下
 		//的数据作为下一次scan读取的数据
-		cmdReader := bufio.NewReader(os.Stdin)
-		cmdStr, err := cmdReader.ReadString('\n')
-		//这里把读取的数据后面的换行去掉，对于Mac是"\r"，Linux下面
-		//是"\n"，Windows下面是"\r\n"，所以为了支持多平台，直接用
-		//"\r\n"作为过滤字符
-		cmdStr = strings.Trim(cmdStr, "\r\n")
+		cmdStr, err := readInput("ftp>")
+		//标准输入已经结束，比如从管道读取命令的时候，这时候退出客户端
+		if err == io.EOF && cmdStr == "" {
+			fmt.Println()
+			this.quit()
+			break
+		}
 
 		//如果输入为空，也就是用户直接按Enter键，那么直接等待下次
 		//交互命令，否则去解析命令并执行
 		if err == nil && cmdStr != "" {
 			this.parseCommand(cmdStr)
-			err := this.executeCommand()
-			if err != nil {
-				fmt.Println("ftp:", err.Error())
-			}
+			printCmdError(this.executeCommand())
 			//重置cmdStr值
 			cmdStr = ""
 		}
 	}
 }
 
+//打印交互命令执行的错误，交互模式下服务器的回复都已经打印过了，
+//所以服务器回复的*ProtocolError不再重复打印
+func printCmdError(err error) {
+	if err == nil || isProtocolError(err) {
+		return
+	}
+	if err == ErrNotConnected {
+		fmt.Println(err.Error())
+	} else {
+		fmt.Println("ftp:", err.Error())
+	}
+}
+
 //解析交互命令
 func (this *GoFtpClient) parseCommand(cmdStr string) {
 	//使用strings包的Fields来分隔命令和参数，因为这个函数
@@ -135,6 +195,7 @@ func (this *GoFtpClient) parseCommand(cmdStr string) {
 	if len(parts) > 0 {
 		this.ftpClientCmd.Name = parts[0]
 		this.ftpClientCmd.Params = parts[1:]
+		_, this.ftpClientCmd.ParamLine = splitCommandLine(cmdStr)
 	}
 }
 
@@ -146,23 +207,97 @@ func (this *GoFtpClient) executeCommand() (err error) {
 	var cmdParams = this.ftpClientCmd.Params
 	switch cmdName {
 	case FCC_CD:
-		this.cwd()
+		err = this.cwd()
 	case FCC_QUIT, FCC_BYE, FCC_EXIT:
-		this.quit()
+		err = this.quit()
 	case FCC_VERSION:
 		this.version()
 	case FCC_CLOSE, FCC_DISCONNECT:
-		this.disconnect()
+		err = this.disconnect()
 	case FCC_OPEN:
-		this.open()
+		err = this.open()
 	case FCC_PWD:
-		this.pwd()
+		err = this.pwd()
 	case FCC_LCD:
-		this.lcd()
+		err = this.lcd()
 	case FCC_LS:
-		this.ls()
+		err = this.ls()
 	case FCC_USER:
-		this.user()
+		err = this.user()
+	case FCC_GET, FCC_RECV:
+		err = this.get()
+	case FCC_PUT, FCC_SEND:
+		err = this.put()
+	case FCC_APPEND:
+		err = this.append()
+	case FCC_SUNIQUE:
+		err = this.sunique()
+	case FCC_ASCII:
+		err = this.ascii()
+	case FCC_BINARY, FCC_IMAGE:
+		err = this.binary()
+	case FCC_TYPE:
+		err = this.transferType()
+	case FCC_PASSIVE:
+		err = this.passive()
+	case FCC_EPSV4:
+		err = this.epsv4()
+	case FCC_SSL:
+		err = this.ssl()
+	case FCC_PROT:
+		err = this.prot()
+	case FCC_MLSD:
+		err = this.mlsd()
+	case FCC_MLST:
+		err = this.mlst()
+	case FCC_FEATURES:
+		err = this.features()
+	case FCC_CHARSET:
+		err = this.charset()
+	case FCC_REGET:
+		err = this.reget()
+	case FCC_REPUT:
+		err = this.reput()
+	case FCC_RESTART:
+		err = this.restart()
+	case FCC_MGET:
+		err = this.mget()
+	case FCC_MPUT:
+		err = this.mput()
+	case FCC_MDELETE:
+		err = this.mdelete()
+	case FCC_MDIR:
+		err = this.mdir()
+	case FCC_GLOB:
+		err = this.glob()
+	case FCC_PROMPT:
+		err = this.prompt()
+	case FCC_MIRROR:
+		err = this.mirror()
+	case FCC_CDUP:
+		err = this.cdup()
+	case FCC_MKDIR:
+		err = this.mkdir()
+	case FCC_RMDIR:
+		err = this.rmdir()
+	case FCC_DELETE:
+		err = this.delete()
+	case FCC_RENAME:
+		err = this.rename()
+	case FCC_CHMOD:
+		err = this.chmod()
+	case FCC_SIZE:
+		err = this.size()
+	case FCC_MODTIME:
+		err = this.modtime()
+	case FCC_LITERAL, FCC_QUOTE:
+		err = this.literal()
+	case FCC_SITE:
+		err = this.site()
+	case FCC_HASH:
+		err = this.hash()
+	case FCC_PROGRESS:
+		err = this.progress()
 	case FCC_USAGE:
 		if len(cmdParams) > 0 {
 			this.cmdUsage(cmdParams...)
@@ -179,56 +314,246 @@ func (this *GoFtpClient) executeCommand() (err error) {
 		err = errors.New("?Invalid command.")
 	}
 
+	//连接已经断开的时候重置连接状态
+	this.ftpClientCmd.checkConnectionLost(err)
+
 	//执行完成，重置命令
 	this.ftpClientCmd.Name = ""
 	this.ftpClientCmd.Params = nil
+	this.ftpClientCmd.ParamLine = ""
 	return
 }
 
 /*
-下面的这些函数其实使用了GoFtpClientCmd来代理执行
-以后我们可以把这些函数的首字母改为大写的，就能够
-导出了，这个包goftp就可以作为一个开源包来使用
+下面的这些函数其实使用了GoFtpClientCmd来代理执行，
+GoFtpClientCmd负责解析交互命令的参数并打印结果，真正
+和ftp服务器的交互都由导出的Client来完成，其他程序可以
+直接使用Client
 */
 
 //断开和ftp的连接
-func (this *GoFtpClient) disconnect() {
-	this.ftpClientCmd.disconnect()
+func (this *GoFtpClient) disconnect() error {
+	return this.ftpClientCmd.disconnect()
 }
 
 //断开和ftp的连接，并且退出客户端程序
-func (this *GoFtpClient) quit() {
+func (this *GoFtpClient) quit() error {
 	this.running = false
-	this.disconnect()
+	return this.disconnect()
 }
 
 //建立到ftp服务器的连接
-func (this *GoFtpClient) open() {
-	this.ftpClientCmd.open()
+func (this *GoFtpClient) open() error {
+	return this.ftpClientCmd.open()
 }
 
 //使用交互式的方式验证登录用户名和密码
-func (this *GoFtpClient) user() {
-	this.ftpClientCmd.user()
+func (this *GoFtpClient) user() error {
+	return this.ftpClientCmd.user()
 }
 
 //输出当前所在远程服务器的目录
-func (this *GoFtpClient) pwd() {
-	this.ftpClientCmd.pwd()
+func (this *GoFtpClient) pwd() error {
+	return this.ftpClientCmd.pwd()
 }
 
 //更改客户端所在远程服务器的目录
-func (this *GoFtpClient) cwd() {
-	this.ftpClientCmd.cwd()
+func (this *GoFtpClient) cwd() error {
+	return this.ftpClientCmd.cwd()
 }
 
 //更改本地工作目录，默认为用户文件目录
-func (this *GoFtpClient) lcd() {
-	this.ftpClientCmd.lcd()
+func (this *GoFtpClient) lcd() error {
+	return this.ftpClientCmd.lcd()
 }
 
 //获取指定目录(无参数时为当前目录)下的文件列表，并可以
 //选择性地将结果输出到指定的文件中
-func (this *GoFtpClient) ls() {
-	this.ftpClientCmd.ls()
+func (this *GoFtpClient) ls() error {
+	return this.ftpClientCmd.ls()
+}
+
+//下载远程文件到本地工作目录，并可以另存为另一个文件名
+func (this *GoFtpClient) get() error {
+	return this.ftpClientCmd.get()
+}
+
+//上传本地文件，并可以另存为另一个文件名
+func (this *GoFtpClient) put() error {
+	return this.ftpClientCmd.put()
+}
+
+//从本地文件的末尾继续下载远程文件
+func (this *GoFtpClient) reget() error {
+	return this.ftpClientCmd.reget()
+}
+
+//从远程文件的末尾继续上传本地文件
+func (this *GoFtpClient) reput() error {
+	return this.ftpClientCmd.reput()
+}
+
+//设置下一次传输开始的位置
+func (this *GoFtpClient) restart() error {
+	return this.ftpClientCmd.restart()
+}
+
+//下载多个远程文件
+func (this *GoFtpClient) mget() error {
+	return this.ftpClientCmd.mget()
+}
+
+//上传多个本地文件
+func (this *GoFtpClient) mput() error {
+	return this.ftpClientCmd.mput()
+}
+
+//删除多个远程文件
+func (this *GoFtpClient) mdelete() error {
+	return this.ftpClientCmd.mdelete()
+}
+
+//打印多个远程目录的详细内容
+func (this *GoFtpClient) mdir() error {
+	return this.ftpClientCmd.mdir()
+}
+
+//切换是否展开通配符
+func (this *GoFtpClient) glob() error {
+	return this.ftpClientCmd.glob()
+}
+
+//切换多个文件的命令是否询问
+func (this *GoFtpClient) prompt() error {
+	return this.ftpClientCmd.prompt()
+}
+
+//同步本地目录和远程目录
+func (this *GoFtpClient) mirror() error {
+	return this.ftpClientCmd.mirror()
+}
+
+//切换到上一级远程目录
+func (this *GoFtpClient) cdup() error {
+	return this.ftpClientCmd.cdup()
+}
+
+//创建远程目录
+func (this *GoFtpClient) mkdir() error {
+	return this.ftpClientCmd.mkdir()
+}
+
+//删除远程目录
+func (this *GoFtpClient) rmdir() error {
+	return this.ftpClientCmd.rmdir()
+}
+
+//删除远程文件
+func (this *GoFtpClient) delete() error {
+	return this.ftpClientCmd.delete()
+}
+
+//重命名远程文件
+func (this *GoFtpClient) rename() error {
+	return this.ftpClientCmd.rename()
+}
+
+//修改远程文件的权限
+func (this *GoFtpClient) chmod() error {
+	return this.ftpClientCmd.chmod()
+}
+
+//显示远程文件的大小
+func (this *GoFtpClient) size() error {
+	return this.ftpClientCmd.size()
+}
+
+//显示或者设置远程文件的修改时间
+func (this *GoFtpClient) modtime() error {
+	return this.ftpClientCmd.modtime()
+}
+
+//把参数原样发送给服务器
+func (this *GoFtpClient) literal() error {
+	return this.ftpClientCmd.literal()
+}
+
+//发送SITE命令
+func (this *GoFtpClient) site() error {
+	return this.ftpClientCmd.site()
+}
+
+//切换传输时是否打印#
+func (this *GoFtpClient) hash() error {
+	return this.ftpClientCmd.hash()
+}
+
+//切换传输时是否显示进度条
+func (this *GoFtpClient) progress() error {
+	return this.ftpClientCmd.progress()
+}
+
+//把本地文件追加到远程文件的末尾
+func (this *GoFtpClient) append() error {
+	return this.ftpClientCmd.append()
+}
+
+//切换上传文件时是否由服务器生成唯一的文件名
+func (this *GoFtpClient) sunique() error {
+	return this.ftpClientCmd.sunique()
+}
+
+//设置文件传输类型为文本模式
+func (this *GoFtpClient) ascii() error {
+	return this.ftpClientCmd.ascii()
+}
+
+//设置文件传输类型为二进制模式
+func (this *GoFtpClient) binary() error {
+	return this.ftpClientCmd.binary()
+}
+
+//打印或者设置文件传输类型
+func (this *GoFtpClient) transferType() error {
+	return this.ftpClientCmd.transferType()
+}
+
+//切换被动模式和主动模式
+func (this *GoFtpClient) passive() error {
+	return this.ftpClientCmd.passive()
+}
+
+//切换IPv4连接的被动模式下是否优先使用EPSV
+func (this *GoFtpClient) epsv4() error {
+	return this.ftpClientCmd.epsv4()
+}
+
+//把控制连接升级为TLS连接
+func (this *GoFtpClient) ssl() error {
+	return this.ftpClientCmd.ssl()
+}
+
+//打印或者设置数据连接的保护级别
+func (this *GoFtpClient) prot() error {
+	return this.ftpClientCmd.prot()
+}
+
+//使用MLSD获取指定目录下机器可读的文件列表
+func (this *GoFtpClient) mlsd() error {
+	return this.ftpClientCmd.mlsd()
+}
+
+//使用MLST获取单个文件机器可读的信息
+func (this *GoFtpClient) mlst() error {
+	return this.ftpClientCmd.mlst()
+}
+
+//打印服务器支持的扩展功能
+func (this *GoFtpClient) features() error {
+	return this.ftpClientCmd.features()
+}
+
+//打印或者设置控制连接使用的字符集
+func (this *GoFtpClient) charset() error {
+	return this.ftpClientCmd.charset()
 }
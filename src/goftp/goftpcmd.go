@@ -8,24 +8,62 @@ import (
 	"net"
 	"os"
 	"os/user"
+	"path"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 //ftp服务器默认监听端口号
 const (
-	FTP_SERVER_DEFAULT_LISTENING_PORT = 21
+	FTP_SERVER_DEFAULT_LISTENING_PORT  = 21
+	FTPS_SERVER_DEFAULT_LISTENING_PORT = 990 //隐式FTPS
 )
 
 const (
-	FC_REQUEST_SUFFIX  string = "\r\n"
-	FC_RESPONSE_BUFFER int    = 1024
+	FC_REQUEST_SUFFIX string = "\r\n"
 )
 
+//ftp服务器的回复码，定义见RFC 959
 const (
-	FC_RESP_CODE_ENTER_PASSIVE_MODE int = 227
+	FC_RESP_CODE_RESTART_MARKER         int = 110
+	FC_RESP_CODE_SERVICE_READY_LATER    int = 120
+	FC_RESP_CODE_DATA_CONN_ALREADY_OPEN int = 125
+	FC_RESP_CODE_FILE_STATUS_OK         int = 150
+	FC_RESP_CODE_COMMAND_OK             int = 200
+	FC_RESP_CODE_SYSTEM_STATUS          int = 211
+	FC_RESP_CODE_FILE_STATUS            int = 213
+	FC_RESP_CODE_SERVICE_READY          int = 220
+	FC_RESP_CODE_SERVICE_CLOSING        int = 221
+	FC_RESP_CODE_TRANSFER_COMPLETE      int = 226
+	FC_RESP_CODE_ENTER_PASSIVE_MODE     int = 227
+	FC_RESP_CODE_LOGGED_IN              int = 230
+	FC_RESP_CODE_FILE_ACTION_OK         int = 250
+	FC_RESP_CODE_PATH_CREATED           int = 257
+	FC_RESP_CODE_NEED_PASSWORD          int = 331
+	FC_RESP_CODE_NEED_ACCOUNT           int = 332
+	FC_RESP_CODE_FILE_ACTION_PENDING    int = 350
+	FC_RESP_CODE_SERVICE_NOT_AVAILABLE  int = 421
+	FC_RESP_CODE_CANNOT_OPEN_DATA_CONN  int = 425
+	FC_RESP_CODE_TRANSFER_ABORTED       int = 426
+	FC_RESP_CODE_FILE_BUSY              int = 450
+	FC_RESP_CODE_LOCAL_ERROR            int = 451
+	FC_RESP_CODE_INSUFFICIENT_STORAGE   int = 452
+	FC_RESP_CODE_SYNTAX_ERROR           int = 500
+	FC_RESP_CODE_SYNTAX_ERROR_IN_PARAMS int = 501
+	FC_RESP_CODE_NOT_IMPLEMENTED        int = 502
+	FC_RESP_CODE_BAD_SEQUENCE           int = 503
+	FC_RESP_CODE_PARAM_NOT_IMPLEMENTED  int = 504
+	FC_RESP_CODE_NOT_LOGGED_IN          int = 530
+	FC_RESP_CODE_NEED_ACCOUNT_FOR_STORE int = 532
+	FC_RESP_CODE_FILE_UNAVAILABLE       int = 550
+	FC_RESP_CODE_PAGE_TYPE_UNKNOWN      int = 551
+	FC_RESP_CODE_EXCEEDED_STORAGE       int = 552
+	FC_RESP_CODE_FILE_NAME_NOT_ALLOWED  int = 553
 )
 
 //定义与ftp服务器进行交互的命令，前缀FC表示Ftp Command
@@ -37,361 +75,1101 @@ const (
 	FC_PWD  string = "PWD"  //PWD
 	FC_CWD  string = "CWD"  //CWD remote_dir
 	FC_LIST string = "LIST" //LIST remote_dir
+	FC_NLST string = "NLST" //NLST remote_dir
 	FC_PASV string = "PASV" //PASV
+	FC_TYPE string = "TYPE" //TYPE A|I
+	FC_RETR string = "RETR" //RETR remote_file
+	FC_STOR string = "STOR" //STOR remote_file
+	FC_APPE string = "APPE" //APPE remote_file
+	FC_STOU string = "STOU" //STOU
+	FC_PORT string = "PORT" //PORT h1,h2,h3,h4,p1,p2
+	FC_EPRT string = "EPRT" //EPRT |af|addr|port|
+	FC_EPSV string = "EPSV" //EPSV
+	FC_AUTH string = "AUTH" //AUTH TLS
+	FC_PBSZ string = "PBSZ" //PBSZ 0
+	FC_PROT string = "PROT" //PROT C|P
+	FC_FEAT string = "FEAT" //FEAT
+	FC_OPTS string = "OPTS" //OPTS command [options]
+	FC_MLSD string = "MLSD" //MLSD remote_dir
+	FC_MLST string = "MLST" //MLST remote_path
+	FC_REST string = "REST" //REST offset
+	FC_SIZE string = "SIZE" //SIZE remote_file
+	FC_ABOR string = "ABOR" //ABOR
+	FC_HASH string = "HASH" //HASH remote_file
+	FC_DELE string = "DELE" //DELE remote_file
+	FC_MKD  string = "MKD"  //MKD remote_dir
+	FC_RMD  string = "RMD"  //RMD remote_dir
+	FC_MDTM string = "MDTM" //MDTM remote_file
+	FC_RNFR string = "RNFR" //RNFR from_name
+	FC_RNTO string = "RNTO" //RNTO to_name
+	FC_CDUP string = "CDUP" //CDUP
+	FC_SITE string = "SITE" //SITE command [args]
+	FC_MFMT string = "MFMT" //MFMT time remote_file
 )
 
 type GoFtpClientCmd struct {
 	Name      string
 	Params    []string
+	ParamLine string //命令名后面的原始文本，literal和site原样发送，不按空白字符拆分
 	Connected bool
 
 	DefaultLocalWorkDir string
 	LocalWorkDir        string
 	Username            string
+	StoreUnique         bool  //上传文件时是否使用STOU让服务器生成唯一的文件名
+	RestartMarker       int64 //restart设置的下一次get或者put开始传输的位置，使用一次后清零
+	NoGlob              bool  //mget、mput、mdelete和mdir是否不展开通配符
+	NoPrompt            bool  //mget、mput、mdelete和mdir是否不对每个文件询问
+	HashMarkSize        int64 //传输时每传输这么多字节打印一个#，为0的时候不打印
+	ShowProgress        bool  //传输时是否显示进度条，打开的时候不打印#
+	ActiveMode          bool  //是否使用主动模式建立数据连接
+	PreferEPSV          bool  //IPv4连接的被动模式下是否也优先使用EPSV
 
-	FtpConn net.Conn
+	CAFile         string //验证服务器证书的CA证书文件
+	CertFile       string //客户端证书文件
+	KeyFile        string //客户端证书的私钥文件
+	KnownHostsFile string //记录服务器证书指纹的文件，为空时不记录
+
+	Charset Charset //控制连接使用的字符集，为nil时不转换
+
+	FtpClient *Client
 
 	GoFtpClientHelp
 }
 
-func (this *GoFtpClientCmd) welcome() {
-	var data []byte = make([]byte, 1024)
-	readCount, err := this.FtpConn.Read(data)
-	if err == nil {
-		fmt.Print(string(data[:readCount]))
-		//提示输入登录名
-		var remoteAddr = this.FtpConn.RemoteAddr().String()
-		var portIndex = strings.LastIndex(remoteAddr, ":")
-		fmt.Printf("Name (%s:%s):", remoteAddr[:portIndex], this.Username)
-		var username string
-		fmt.Scanln(&username)
-		this.sendCmdRequest([]string{FC_USER, username})
-		this.recvCmdResponse()
-		//提示输入登录密码
-		fmt.Print("Password:")
-		var password string
-		fmt.Scanln(&password)
-		this.sendCmdRequest([]string{FC_PASS, password})
-		this.recvCmdResponse()
-	} else {
-		fmt.Println(err)
+//连接到ftp服务器，连接成功后打印欢迎信息并提示用户登录。ftpHost前面
+//可以带有ftps://，表示使用隐式FTPS，ftpPort为0时使用默认端口号
+func (this *GoFtpClientCmd) connect(ftpHost string, ftpPort int, implicitTLS bool) (err error) {
+	ftpHost, schemeTLS, err := splitScheme(ftpHost)
+	if err != nil {
+		return
+	}
+	var addr = ftpHost
+	if ftpPort != 0 {
+		addr = net.JoinHostPort(ftpHost, strconv.Itoa(ftpPort))
 	}
+	var knownHosts *KnownHosts
+	if this.KnownHostsFile != "" {
+		if knownHosts, err = LoadKnownHosts(this.KnownHostsFile); err != nil {
+			return
+		}
+	}
+	client, err := Dial(addr, &DialOptions{
+		ReplyLog:      console,
+		ActiveMode:    this.ActiveMode,
+		PreferEPSV:    this.PreferEPSV,
+		ImplicitTLS:   implicitTLS || schemeTLS,
+		CAFile:        this.CAFile,
+		CertFile:      this.CertFile,
+		KeyFile:       this.KeyFile,
+		KnownHosts:    knownHosts,
+		TrustCallback: this.trustCert,
+		Charset:       this.Charset,
+	})
+	if err != nil {
+		return
+	}
+	var remoteAddr = client.RemoteAddr().(*net.TCPAddr)
+	fmt.Println("Connected to", remoteAddr.IP, ".")
+	//获取操作系统当前登录用户
+	var sysUser, _ = user.Current()
+	this.FtpClient = client
+	this.Connected = true
+	this.Username = sysUser.Username
+	this.DefaultLocalWorkDir = sysUser.HomeDir
+	this.LocalWorkDir = sysUser.HomeDir
+
+	return this.welcome()
 }
 
-func (this *GoFtpClientCmd) sendCmdRequest(ftpParams []string) {
-	if this.Connected {
-		var sendData = fmt.Sprint(strings.Join(ftpParams, " "), FC_REQUEST_SUFFIX)
-		this.FtpConn.Write([]byte(sendData))
-	} else {
-		fmt.Println("Not connected.")
+//服务器证书没有通过CA验证的时候决定是否信任，第一次连接时直接信任并
+//记录证书指纹，证书发生变化时询问用户，批处理模式下直接拒绝
+func (this *GoFtpClientCmd) trustCert(hostport string, fingerprint string, knownFingerprint string) bool {
+	if knownFingerprint == "" {
+		fmt.Printf("Warning: Adding `%s' (%s) to the list of known hosts.\n", hostport, fingerprint)
+		return true
+	}
+	fmt.Println("WARNING: SERVER CERTIFICATE HAS CHANGED!")
+	fmt.Printf("The certificate fingerprint for `%s' is\n%s\n", hostport, fingerprint)
+	fmt.Printf("but the known fingerprint is\n%s\n", knownFingerprint)
+	if !isInteractive() {
+		fmt.Println("Certificate verification failed in batch mode.")
+		return false
 	}
+	var answer, _ = readInput("Are you sure you want to continue connecting (yes/no)? ")
+	return strings.ToLower(strings.TrimSpace(answer)) == "yes"
 }
 
-func (this *GoFtpClientCmd) recvCmdResponse() (recvData string) {
-	if this.Connected {
-		var recvBytes []byte = make([]byte, FC_RESPONSE_BUFFER)
-		readCount, err := this.FtpConn.Read(recvBytes)
-		if err == nil {
-			recvData = string(recvBytes[:readCount])
-			fmt.Print(string(recvData))
-		} else {
-			fmt.Println("ftp:", err)
-		}
+func (this *GoFtpClientCmd) welcome() (err error) {
+	//提示输入登录名
+	var remoteAddr = this.FtpClient.RemoteAddr().(*net.TCPAddr)
+	var username, _ = readInput(fmt.Sprintf("Name (%s:%s):", remoteAddr.IP, this.Username))
+	username = strings.TrimSpace(username)
+	if username == "" {
+		username = this.Username
+	}
+	//提示输入登录密码
+	var password, _ = readInput("Password:")
+	return this.login(username, password, "")
+}
+
+//登录，account不为空的时候还会发送账户信息
+func (this *GoFtpClientCmd) login(username string, password string, account string) (err error) {
+	err = this.FtpClient.Login(username, password)
+	if err == nil && account != "" {
+		err = this.FtpClient.Account(account)
+	}
+	if errors.Is(err, ErrNotLoggedIn) {
+		fmt.Println("Login failed.")
+	}
+	return
+}
+
+//检查是否已经连接到ftp服务器
+func (this *GoFtpClientCmd) checkConnected() (err error) {
+	if !this.Connected {
+		err = ErrNotConnected
 	}
 	return
 }
 
-func (this *GoFtpClientCmd) open() {
+//服务器回复421或者控制连接已经断开的时候，客户端这边也关闭连接
+func (this *GoFtpClientCmd) checkConnectionLost(err error) {
+	if this.FtpClient == nil || err == nil {
+		return
+	}
+	if isConnectionLost(err) {
+		fmt.Println("Connection closed by remote host.")
+		this.FtpClient.Close()
+		this.reset()
+	}
+}
+
+func (this *GoFtpClientCmd) open() (err error) {
 	if this.Connected {
-		var remoteAddr = this.FtpConn.RemoteAddr().String()
-		var portIndex = strings.LastIndex(remoteAddr, ":")
-		fmt.Println("Already connected to ", remoteAddr[:portIndex], ", use close first.")
-	} else {
-		var paramCount = len(this.Params)
-		var ftpHost string
-		var ftpPort int
-		if paramCount == 0 {
-			fmt.Print("(To) ")
-			cmdReader := bufio.NewReader(os.Stdin)
-			cmdStr, err := cmdReader.ReadString('\n')
-			cmdStr = strings.Trim(cmdStr, "\r\n")
-			if err == nil && cmdStr != "" {
-				cmdParts := strings.Fields(cmdStr)
-				cmdPartCount := len(cmdParts)
-				if cmdPartCount == 1 {
-					ftpHost = cmdParts[0]
-					ftpPort = FTP_SERVER_DEFAULT_LISTENING_PORT
-				} else if cmdPartCount == 2 {
-					ftpHost = cmdParts[0]
-					port, err := strconv.Atoi(cmdParts[1])
-					if err != nil {
-						this.cmdUsage(this.Name)
-					} else {
-						ftpPort = port
-					}
-				}
-			} else {
-				this.cmdUsage(this.Name)
-			}
-		} else if paramCount == 1 {
-			ftpHost = this.Params[0]
-			ftpPort = FTP_SERVER_DEFAULT_LISTENING_PORT
-		} else if paramCount == 2 {
-			ftpHost = this.Params[0]
-			port, err := strconv.Atoi(this.Params[1])
-			if err != nil {
-				this.cmdUsage(this.Name)
-			} else {
-				ftpPort = port
-			}
+		var remoteAddr = this.FtpClient.RemoteAddr().(*net.TCPAddr)
+		fmt.Println("Already connected to ", remoteAddr.IP, ", use close first.")
+		return
+	}
+	var params = this.Params
+	if len(params) == 0 {
+		cmdStr, _ := readInput("(To) ")
+		if cmdStr == "" {
+			this.cmdUsage(this.Name)
+			return
 		}
+		params = strings.Fields(cmdStr)
+	}
+	//-implicit表示使用隐式FTPS
+	var implicitTLS bool
+	if len(params) > 0 && params[0] == "-implicit" {
+		implicitTLS = true
+		params = params[1:]
+	}
 
-		//建立ftp连接
-		if ftpHost != "" {
-			ips, lookupErr := net.LookupIP(ftpHost)
-			if lookupErr != nil {
-				fmt.Println("ftp: Can't lookup host `", ftpHost, "'")
-			} else {
-				var port = strconv.Itoa(ftpPort)
-				for _, ip := range ips {
-					conn, connErr := net.DialTimeout("tcp", net.JoinHostPort(ip.String(), port),
-						time.Duration(DIAL_FTP_SERVER_TIMEOUT_SECONDS)*time.Second)
-					if connErr != nil {
-						fmt.Println("Trying ", ip, "...")
-						fmt.Println("ftp:", connErr.Error())
-					} else {
-						fmt.Println("Connected to", ip, ".")
-						var sysUser, _ = user.Current()
-						this.FtpConn = conn
-						this.Connected = true
-						this.Username = sysUser.Username
-						this.DefaultLocalWorkDir = sysUser.HomeDir
-						this.LocalWorkDir = sysUser.HomeDir
-
-						this.welcome()
-						break
-					}
-				}
-			}
+	var ftpHost string
+	var ftpPort int
+	switch len(params) {
+	case 1:
+		ftpHost = params[0]
+	case 2:
+		port, convErr := strconv.Atoi(params[1])
+		if convErr != nil {
+			this.cmdUsage(this.Name)
+			return
 		}
+		ftpHost = params[0]
+		ftpPort = port
+	default:
+		this.cmdUsage(this.Name)
+		return
 	}
-}
 
-func (this *GoFtpClientCmd) parseCmdResponse(respData string) (ftpRespCode int, err error) {
-	var recvDataParts = strings.Fields(respData)
-	if len(recvDataParts) > 0 {
-		ftpRespCode, err = strconv.Atoi(recvDataParts[0])
-	}
-	return
+	//建立ftp连接
+	return this.connect(ftpHost, ftpPort, implicitTLS)
 }
 
-func (this *GoFtpClientCmd) lcd() {
+func (this *GoFtpClientCmd) lcd() (err error) {
 	var paramCount = len(this.Params)
-	if paramCount == 0 || paramCount == 1 {
-		if paramCount == 0 {
-			this.LocalWorkDir = this.DefaultLocalWorkDir
-			fmt.Println("Local directory now:", this.LocalWorkDir)
-		} else {
-			var path = this.Params[0]
-			if !filepath.IsAbs(path) {
-				path = filepath.Join(this.DefaultLocalWorkDir, path)
-			}
-			fiInfo, err := os.Stat(path)
-			if err != nil {
-				fmt.Println("ftp:", err.Error())
-			} else {
-				if fiInfo.IsDir() {
-					this.LocalWorkDir = path
-					fmt.Println("Local directory now:", path)
-				} else {
-					fmt.Println("ftp: Can't chdir `", path, "': No such file or directory")
-				}
-			}
+	if paramCount == 0 {
+		this.LocalWorkDir = this.DefaultLocalWorkDir
+		fmt.Println("Local directory now:", this.LocalWorkDir)
+	} else if paramCount == 1 {
+		var path = this.Params[0]
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(this.DefaultLocalWorkDir, path)
 		}
+		fiInfo, statErr := os.Stat(path)
+		if statErr != nil {
+			return statErr
+		}
+		if !fiInfo.IsDir() {
+			return fmt.Errorf("Can't chdir `%s': No such file or directory", path)
+		}
+		this.LocalWorkDir = path
+		fmt.Println("Local directory now:", path)
 	} else {
 		this.cmdUsage(this.Name)
 	}
+	return
 }
 
-func (this *GoFtpClientCmd) user() {
+func (this *GoFtpClientCmd) user() (err error) {
+	if err = this.checkConnected(); err != nil {
+		return
+	}
 	var paramCount = len(this.Params)
 	var username string
 	var password string
 	var account string
 	if paramCount == 0 {
-		fmt.Print("Username:")
-		fmt.Scanln(&username)
-		username = strings.Trim(username, "\r\n")
+		username, _ = readInput("Username:")
+		username = strings.TrimSpace(username)
 		if username == "" {
 			this.cmdUsage(this.Name)
-		} else {
-			this.sendCmdRequest([]string{FC_USER, username})
-			this.recvCmdResponse()
-			fmt.Print("Password:")
-			fmt.Scanln(&password)
-			this.sendCmdRequest([]string{FC_PASS, password})
-			this.recvCmdResponse()
+			return
 		}
+		password, _ = readInput("Password:")
 	} else if paramCount == 1 {
 		username = this.Params[0]
-		this.sendCmdRequest([]string{FC_USER, username})
-		this.recvCmdResponse()
-		fmt.Print("Password:")
-		fmt.Scanln(&password)
-		this.sendCmdRequest([]string{FC_PASS, password})
-		this.recvCmdResponse()
+		password, _ = readInput("Password:")
 	} else if paramCount == 2 {
 		username = this.Params[0]
 		password = this.Params[1]
-		this.sendCmdRequest([]string{FC_USER, username})
-		this.recvCmdResponse()
-		this.sendCmdRequest([]string{FC_PASS, password})
-		this.recvCmdResponse()
 	} else if paramCount == 3 {
 		username = this.Params[0]
 		password = this.Params[1]
 		account = this.Params[2]
-		this.sendCmdRequest([]string{FC_USER, username})
-		this.recvCmdResponse()
-		this.sendCmdRequest([]string{FC_PASS, password})
-		this.recvCmdResponse()
-		this.sendCmdRequest([]string{FC_ACCT, account})
-		this.recvCmdResponse()
 	} else {
 		this.cmdUsage(this.Name)
+		return
 	}
-
+	return this.login(username, password, account)
 }
 
-func (this *GoFtpClientCmd) pwd() {
-	this.sendCmdRequest([]string{FC_PWD})
-	this.recvCmdResponse()
+func (this *GoFtpClientCmd) pwd() (err error) {
+	if err = this.checkConnected(); err != nil {
+		return
+	}
+	_, err = this.FtpClient.Pwd()
+	return
 }
 
-func (this *GoFtpClientCmd) cwd() {
+func (this *GoFtpClientCmd) cwd() (err error) {
+	if err = this.checkConnected(); err != nil {
+		return
+	}
 	var paramCount = len(this.Params)
+	var remoteDir string
 	if paramCount == 0 {
-		fmt.Println("(remote-directory)")
-		var remoteDir string
-		fmt.Scanln(&remoteDir)
-		if remoteDir != "" {
-			this.sendCmdRequest([]string{FC_CWD, remoteDir})
-			this.recvCmdResponse()
-		} else {
+		remoteDir, _ = readInput("(remote-directory) ")
+		remoteDir = strings.TrimSpace(remoteDir)
+		if remoteDir == "" {
 			this.cmdUsage(this.Name)
+			return
 		}
 	} else if paramCount > 1 {
 		this.cmdUsage(this.Name)
+		return
 	} else {
-		this.sendCmdRequest([]string{FC_CWD, this.Params[0]})
-		this.recvCmdResponse()
+		remoteDir = this.Params[0]
+	}
+	return this.FtpClient.Cwd(remoteDir)
+}
+
+//把参数原样发送给服务器，服务器的回复会全部打印出来，命令后面的空白字符
+//不会被合并或者去掉
+func (this *GoFtpClientCmd) literal() (err error) {
+	if err = this.checkConnected(); err != nil {
+		return
+	}
+	var line = this.ParamLine
+	if strings.TrimSpace(line) == "" {
+		line, _ = readInput("(command line to send) ")
+	}
+	var cmd, args = splitCommandLine(line)
+	if cmd == "" {
+		this.cmdUsage(this.Name)
+		return
+	}
+	if args == "" {
+		_, err = this.FtpClient.Raw(cmd)
+	} else {
+		_, err = this.FtpClient.Raw(cmd, args)
+	}
+	return
+}
+
+//把一行文本拆分为第一个词和后面的部分，只去掉开头的空白字符和第一个词后面
+//的一个空白字符，后面的部分原样保留
+func splitCommandLine(line string) (name string, rest string) {
+	line = strings.TrimLeftFunc(line, unicode.IsSpace)
+	var index = strings.IndexFunc(line, unicode.IsSpace)
+	if index == -1 {
+		return line, ""
+	}
+	_, size := utf8.DecodeRuneInString(line[index:])
+	return line[:index], line[index+size:]
+}
+
+//发送SITE命令，参数是服务器自己定义的子命令
+func (this *GoFtpClientCmd) site() (err error) {
+	if err = this.checkConnected(); err != nil {
+		return
+	}
+	var args = strings.TrimLeftFunc(this.ParamLine, unicode.IsSpace)
+	if args == "" {
+		line, _ := readInput("(arguments to SITE command) ")
+		if args = strings.TrimLeftFunc(line, unicode.IsSpace); args == "" {
+			this.cmdUsage(this.Name)
+			return
+		}
+	}
+	_, err = this.FtpClient.Raw(FC_SITE, args)
+	return
+}
+
+//打印远程文件的大小
+func (this *GoFtpClientCmd) size() (err error) {
+	if err = this.checkConnected(); err != nil {
+		return
+	}
+	params, ok := this.readParams("(remote-file) ")
+	if !ok {
+		return
+	}
+	_, err = this.FtpClient.Size(params[0])
+	return
+}
+
+//打印远程文件的修改时间，指定了YYYYMMDDHHMMSS格式的UTC时间的时候，
+//把远程文件的修改时间设置为这个时间
+func (this *GoFtpClientCmd) modtime() (err error) {
+	if err = this.checkConnected(); err != nil {
+		return
+	}
+	if len(this.Params) == 2 {
+		modTime, parseErr := parseMlsxTime(this.Params[1])
+		if parseErr != nil {
+			return fmt.Errorf("modtime: Invalid time `%s'", this.Params[1])
+		}
+		return this.FtpClient.SetModTime(this.Params[0], modTime)
 	}
+	params, ok := this.readParams("(remote-file) ")
+	if !ok {
+		return
+	}
+	modTime, err := this.FtpClient.ModTime(params[0])
+	if err == nil {
+		fmt.Printf("%s\t%s\n", params[0], modTime.Format("01/02/2006 15:04:05 MST"))
+	}
+	return
 }
 
-func (this *GoFtpClientCmd) ls() {
+//读取命令的参数，参数数量必须和prompts相同，一个参数都没有的时候依次
+//提示输入，参数不对的时候打印命令的用法并返回false
+func (this *GoFtpClientCmd) readParams(prompts ...string) (params []string, ok bool) {
 	var paramCount = len(this.Params)
-	if paramCount >= 0 && paramCount <= 2 {
-		var resultOutputFile string
-		var remoteDir string
-		if paramCount == 1 {
-			remoteDir = this.Params[0]
-		} else if paramCount == 2 {
-			remoteDir = this.Params[0]
-			resultOutputFile = this.Params[1]
+	if paramCount == len(prompts) {
+		return this.Params, true
+	}
+	if paramCount > 0 {
+		this.cmdUsage(this.Name)
+		return
+	}
+	for _, prompt := range prompts {
+		param, _ := readInput(prompt)
+		param = strings.TrimSpace(param)
+		if param == "" {
+			this.cmdUsage(this.Name)
+			return
 		}
-		var outputFile *os.File
-		var err error
-		if resultOutputFile != "" {
-			if !filepath.IsAbs(resultOutputFile) {
-				resultOutputFile = filepath.Join(this.LocalWorkDir, resultOutputFile)
-			}
-			outputFile, err = os.Create(resultOutputFile)
-			if err != nil {
-				fmt.Println("ftp: Can't access `", resultOutputFile, "': No such file or directory")
-			}
+		params = append(params, param)
+	}
+	return params, true
+}
+
+func (this *GoFtpClientCmd) cdup() (err error) {
+	if err = this.checkConnected(); err != nil {
+		return
+	}
+	if len(this.Params) > 0 {
+		this.cmdUsage(this.Name)
+		return
+	}
+	return this.FtpClient.Cdup()
+}
+
+func (this *GoFtpClientCmd) mkdir() (err error) {
+	if err = this.checkConnected(); err != nil {
+		return
+	}
+	params, ok := this.readParams("(directory-name) ")
+	if !ok {
+		return
+	}
+	_, err = this.FtpClient.Mkdir(params[0])
+	return
+}
+
+func (this *GoFtpClientCmd) rmdir() (err error) {
+	if err = this.checkConnected(); err != nil {
+		return
+	}
+	params, ok := this.readParams("(directory-name) ")
+	if !ok {
+		return
+	}
+	return this.FtpClient.Rmdir(params[0])
+}
+
+func (this *GoFtpClientCmd) delete() (err error) {
+	if err = this.checkConnected(); err != nil {
+		return
+	}
+	params, ok := this.readParams("(remote-file) ")
+	if !ok {
+		return
+	}
+	return this.FtpClient.Delete(params[0])
+}
+
+func (this *GoFtpClientCmd) rename() (err error) {
+	if err = this.checkConnected(); err != nil {
+		return
+	}
+	params, ok := this.readParams("(from-name) ", "(to-name) ")
+	if !ok {
+		return
+	}
+	return this.FtpClient.Rename(params[0], params[1])
+}
+
+//修改远程文件的权限，权限使用八进制表示，比如644或者4755
+func (this *GoFtpClientCmd) chmod() (err error) {
+	if err = this.checkConnected(); err != nil {
+		return
+	}
+	params, ok := this.readParams("(mode) ", "(remote-file) ")
+	if !ok {
+		return
+	}
+	mode, parseErr := strconv.ParseUint(params[0], 8, 32)
+	if parseErr != nil || mode > 07777 {
+		return fmt.Errorf("chmod: Invalid mode `%s'", params[0])
+	}
+	return this.FtpClient.Chmod(params[1], unixFileMode(uint32(mode), ENTRY_TYPE_FILE))
+}
+
+func (this *GoFtpClientCmd) ls() (err error) {
+	return this.listTo(FC_LIST)
+}
+
+func (this *GoFtpClientCmd) mlsd() (err error) {
+	if err = this.checkConnected(); err != nil {
+		return
+	}
+	if err = this.requireFeature(FEAT_MLST); err != nil {
+		return
+	}
+	return this.listTo(FC_MLSD)
+}
+
+//执行LIST或者MLSD命令，把服务器返回的原始行输出到标准输出或者
+//参数指定的本地文件中
+func (this *GoFtpClientCmd) listTo(ftpCmd string) (err error) {
+	if err = this.checkConnected(); err != nil {
+		return
+	}
+	var paramCount = len(this.Params)
+	if paramCount > 2 {
+		this.cmdUsage(this.Name)
+		return
+	}
+	var resultOutputFile string
+	var remoteDir string
+	if paramCount >= 1 {
+		remoteDir = this.Params[0]
+	}
+	if paramCount == 2 {
+		resultOutputFile = this.Params[1]
+	}
+	var output = os.Stdout
+	if resultOutputFile != "" {
+		if !filepath.IsAbs(resultOutputFile) {
+			resultOutputFile = filepath.Join(this.LocalWorkDir, resultOutputFile)
 		}
+		outputFile, createErr := os.Create(resultOutputFile)
+		if createErr != nil {
+			return fmt.Errorf("Can't access `%s': No such file or directory", resultOutputFile)
+		}
+		defer outputFile.Close()
+		output = outputFile
+	}
+
+	lines, err := this.FtpClient.dataLines(ftpCmd, remoteDir)
+	if err != nil {
+		return
+	}
+	var bWriter = bufio.NewWriter(output)
+	for _, line := range lines {
+		bWriter.WriteString(line + "\n")
+	}
+	return bWriter.Flush()
+}
+
+func (this *GoFtpClientCmd) get() (err error) {
+	return this.download(false)
+}
+
+func (this *GoFtpClientCmd) reget() (err error) {
+	return this.download(true)
+}
 
-		if err == nil {
-			pasvHost, pasvPort, ftpRespCode, errPasv := this.pasv()
-			if errPasv == nil {
-				if pasvHost != "" && ftpRespCode == FC_RESP_CODE_ENTER_PASSIVE_MODE {
-					this.sendCmdRequest([]string{FC_LIST, remoteDir})
-					go this.recvCmdResponse()
-					var pasvRespData = this.getPasvData(pasvHost, pasvPort)
-					if outputFile != nil {
-						var bWriter = bufio.NewWriter(outputFile)
-						bWriter.WriteString(string(pasvRespData))
-						bWriter.Flush()
-						outputFile.Close()
-					} else {
-						fmt.Print(string(pasvRespData))
-					}
-					this.recvCmdResponse()
-				}
+//下载远程文件，resume为true的时候从本地文件的末尾继续下载
+func (this *GoFtpClientCmd) download(resume bool) (err error) {
+	if err = this.checkConnected(); err != nil {
+		return
+	}
+	var opts = &TransferOptions{Resume: resume, Offset: this.RestartMarker}
+	//-n segments表示用多个连接分段下载，-verify表示下载完成后比较哈希值，
+	//-r表示递归下载目录，-L表示递归下载时跟随符号链接，-p表示保留修改时间
+	var params = this.Params
+	var recursive, followSymlinks bool
+	for len(params) > 0 && strings.HasPrefix(params[0], "-") {
+		switch {
+		case params[0] == "-r":
+			recursive = true
+			params = params[1:]
+		case params[0] == "-L":
+			followSymlinks = true
+			params = params[1:]
+		case params[0] == "-n" && len(params) > 1:
+			segments, parseErr := strconv.Atoi(params[1])
+			if parseErr != nil || segments < 1 {
+				return fmt.Errorf("%s: Invalid segment count `%s'", this.Name, params[1])
 			}
+			opts.Segments = segments
+			params = params[2:]
+		case params[0] == "-verify":
+			opts.VerifyHash = true
+			params = params[1:]
+		case params[0] == "-p":
+			opts.PreserveModTime = true
+			params = params[1:]
+		default:
+			this.cmdUsage(this.Name)
+			return
 		}
+	}
+	var paramCount = len(params)
+	var remoteFile string
+	var localFile string
+	if paramCount == 0 {
+		remoteFile, _ = readInput("(remote-file) ")
+		remoteFile = strings.TrimSpace(remoteFile)
+		if remoteFile == "" {
+			this.cmdUsage(this.Name)
+			return
+		}
+		localFile, _ = readInput("(local-file) ")
+		localFile = strings.TrimSpace(localFile)
+	} else if paramCount == 1 {
+		remoteFile = params[0]
+	} else if paramCount == 2 {
+		remoteFile = params[0]
+		localFile = params[1]
 	} else {
 		this.cmdUsage(this.Name)
+		return
 	}
+	//没有指定本地文件名的时候，使用远程文件的文件名
+	if localFile == "" {
+		localFile = path.Base(remoteFile)
+	}
+	this.RestartMarker = 0
+	if recursive {
+		//下载远程根目录的时候放到本地工作目录
+		if localFile == "/" {
+			localFile = "."
+		}
+		opts.Offset = 0
+		return this.getTree(remoteFile, localFile, &TreeOptions{TransferOptions: *opts, FollowSymlinks: followSymlinks})
+	}
+	return this.getFile(remoteFile, localFile, opts)
 }
 
-func (this *GoFtpClientCmd) pasv() (pasvHost string, pasvPort int, ftpRespCode int, err error) {
-	if this.Connected {
-		this.sendCmdRequest([]string{FC_PASV})
-		var recvData = this.recvCmdResponse()
-		var startIndex = strings.Index(recvData, "(")
-		var endIndex = strings.LastIndex(recvData, ")")
-		if startIndex == -1 || endIndex == -1 {
-			err = errors.New("ftp: PASV command failed.")
-		} else {
-			var pasvDataStr = recvData[startIndex+1 : endIndex]
-			var pasvDataParts = strings.Split(pasvDataStr, ",")
-			pasvHost = strings.Join(pasvDataParts[:4], ".")
-			var p1, p2 int
-			p1, err = strconv.Atoi(pasvDataParts[4])
-			p2, err = strconv.Atoi(pasvDataParts[5])
-			pasvPort = p1*256 + p2
-
-			ftpRespCode, err = this.parseCmdResponse(recvData)
+//递归下载远程目录，打印每个文件的结果和汇总
+func (this *GoFtpClientCmd) getTree(remoteDir string, localDir string, opts *TreeOptions) (err error) {
+	fmt.Println("local:", localDir, "remote:", remoteDir)
+	var result = &multiResult{cmdName: this.Name}
+	opts.Progress = result.addTree
+	_, err = this.FtpClient.DownloadDir(remoteDir, this.localPath(localDir), opts)
+	result.summary()
+	return
+}
+
+//递归上传本地目录，打印每个文件的结果和汇总
+func (this *GoFtpClientCmd) putTree(localDir string, remoteDir string, opts *TreeOptions) (err error) {
+	fmt.Println("local:", localDir, "remote:", remoteDir)
+	var result = &multiResult{cmdName: this.Name}
+	opts.Progress = result.addTree
+	_, err = this.FtpClient.UploadDir(this.localPath(localDir), remoteDir, opts)
+	result.summary()
+	return
+}
+
+//下载一个远程文件并打印传输的统计信息
+func (this *GoFtpClientCmd) getFile(remoteFile string, localFile string, opts *TransferOptions) (err error) {
+	var options TransferOptions
+	if opts != nil {
+		options = *opts
+	}
+	var display = this.newTransferDisplay()
+	options.Progress = display.callback()
+	fmt.Println("local:", localFile, "remote:", remoteFile)
+	var startTime = time.Now()
+	n, err := this.FtpClient.DownloadFile(remoteFile, this.localPath(localFile), &options)
+	display.finish()
+	if err == nil {
+		printTransferStat(n, "received", time.Since(startTime))
+	}
+	return
+}
+
+func (this *GoFtpClientCmd) put() (err error) {
+	return this.upload(FC_STOR, false)
+}
+
+func (this *GoFtpClientCmd) reput() (err error) {
+	return this.upload(FC_STOR, true)
+}
+
+func (this *GoFtpClientCmd) append() (err error) {
+	return this.upload(FC_APPE, false)
+}
+
+//上传本地文件，ftpCmd为FC_STOR或者FC_APPE，打开了sunique的时候
+//FC_STOR会使用STOU上传。resume为true或者设置了restart的时候，
+//从远程文件的末尾或者restart的位置继续上传。append总是追加到远程文件
+//的末尾，设置了restart的时候拒绝执行，restart的位置留给下一次get或者put
+func (this *GoFtpClientCmd) upload(ftpCmd string, resume bool) (err error) {
+	if err = this.checkConnected(); err != nil {
+		return
+	}
+	if ftpCmd == FC_APPE && this.RestartMarker > 0 {
+		return fmt.Errorf("%s: Can't restart append at %d, use put or reput instead", this.Name, this.RestartMarker)
+	}
+	//-r表示递归上传目录，-L表示递归上传时跟随符号链接，-p表示保留修改时间，
+	//append不能使用
+	var params = this.Params
+	var recursive, followSymlinks, preserve bool
+	for len(params) > 0 && strings.HasPrefix(params[0], "-") && ftpCmd == FC_STOR {
+		switch params[0] {
+		case "-r":
+			recursive = true
+		case "-L":
+			followSymlinks = true
+		case "-p":
+			preserve = true
+		default:
+			this.cmdUsage(this.Name)
+			return
+		}
+		params = params[1:]
+	}
+	var paramCount = len(params)
+	var localFile string
+	var remoteFile string
+	if paramCount == 0 {
+		localFile, _ = readInput("(local-file) ")
+		localFile = strings.TrimSpace(localFile)
+		if localFile == "" {
+			this.cmdUsage(this.Name)
+			return
 		}
+		remoteFile, _ = readInput("(remote-file) ")
+		remoteFile = strings.TrimSpace(remoteFile)
+	} else if paramCount == 1 {
+		localFile = params[0]
+	} else if paramCount == 2 {
+		localFile = params[0]
+		remoteFile = params[1]
 	} else {
-		fmt.Println("Not connected.")
+		this.cmdUsage(this.Name)
+		return
+	}
+	//没有指定远程文件名的时候，使用本地文件的文件名
+	if remoteFile == "" {
+		remoteFile = filepath.Base(localFile)
+	}
+	var opts = &TransferOptions{Resume: resume, Offset: this.RestartMarker, PreserveModTime: preserve}
+	this.RestartMarker = 0
+	if recursive {
+		opts.Offset = 0
+		return this.putTree(localFile, remoteFile, &TreeOptions{TransferOptions: *opts, FollowSymlinks: followSymlinks})
+	}
+	return this.putFile(ftpCmd, localFile, remoteFile, opts)
+}
+
+//上传一个本地文件并打印传输的统计信息，需要断点续传或者保留修改时间的
+//时候使用UploadFile
+func (this *GoFtpClientCmd) putFile(ftpCmd string, localFile string, remoteFile string, opts *TransferOptions) (err error) {
+	var display = this.newTransferDisplay()
+	if ftpCmd == FC_STOR && (opts.Resume || opts.Offset > 0 || opts.PreserveModTime && !this.StoreUnique) {
+		var options = *opts
+		options.Progress = display.callback()
+		fmt.Println("local:", localFile, "remote:", remoteFile)
+		var startTime = time.Now()
+		n, _, uploadErr := this.FtpClient.UploadFile(this.localPath(localFile), remoteFile, &options)
+		display.finish()
+		if uploadErr == nil {
+			printTransferStat(n, "sent", time.Since(startTime))
+		}
+		return uploadErr
+	}
+	file, err := os.Open(this.localPath(localFile))
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	var reader io.Reader = file
+	if display != nil {
+		var total int64 = -1
+		if info, statErr := file.Stat(); statErr == nil {
+			total = info.Size()
+		}
+		reader = NewProgressReader(file, total, display.callback())
+	}
+	fmt.Println("local:", localFile, "remote:", remoteFile)
+
+	var startTime = time.Now()
+	var n int64
+	var uniqueName string
+	if ftpCmd == FC_APPE {
+		n, _, err = this.FtpClient.Appe(remoteFile, reader)
+	} else if this.StoreUnique {
+		uniqueName, n, _, err = this.FtpClient.Stou(reader)
+	} else {
+		n, _, err = this.FtpClient.Stor(remoteFile, reader)
+	}
+	display.finish()
+	if err == nil && uniqueName != "" {
+		fmt.Println("Stored as", uniqueName)
+	}
+	if err == nil {
+		printTransferStat(n, "sent", time.Since(startTime))
 	}
 	return
 }
 
-func (this *GoFtpClientCmd) getPasvData(pasvHost string, pasvPort int) (pasvRespData []byte) {
-	pasvConn, pasvConnErr := net.DialTimeout("tcp", net.JoinHostPort(pasvHost, strconv.Itoa(pasvPort)),
-		time.Duration(DIAL_FTP_SERVER_TIMEOUT_SECONDS)*time.Second)
-	if pasvConnErr != nil {
-		fmt.Println(pasvConnErr.Error())
+//交互命令中使用的传输类型名称
+var transferTypeNames = map[string]TransferType{
+	"ascii":  TRANSFER_TYPE_ASCII,
+	"binary": TRANSFER_TYPE_BINARY,
+	"image":  TRANSFER_TYPE_BINARY,
+	"local":  TRANSFER_TYPE_LOCAL8,
+}
+
+func (this *GoFtpClientCmd) ascii() (err error) {
+	return this.setType(TRANSFER_TYPE_ASCII)
+}
+
+func (this *GoFtpClientCmd) binary() (err error) {
+	return this.setType(TRANSFER_TYPE_BINARY)
+}
+
+//没有参数的时候打印当前的传输类型，否则设置传输类型
+func (this *GoFtpClientCmd) transferType() (err error) {
+	if err = this.checkConnected(); err != nil {
+		return
+	}
+	switch len(this.Params) {
+	case 0:
+		this.printType()
+	case 1:
+		transferType, ok := transferTypeNames[strings.ToLower(this.Params[0])]
+		if !ok {
+			return fmt.Errorf("%s: unknown mode.", this.Params[0])
+		}
+		err = this.setType(transferType)
+	default:
+		this.cmdUsage(this.Name)
+	}
+	return
+}
+
+//设置传输类型，TYPE命令会在下一次传输之前发送
+func (this *GoFtpClientCmd) setType(transferType TransferType) (err error) {
+	if err = this.checkConnected(); err != nil {
+		return
+	}
+	if err = this.FtpClient.Type(transferType); err == nil {
+		this.printType()
+	}
+	return
+}
+
+func (this *GoFtpClientCmd) printType() {
+	var transferType = this.FtpClient.TransferType()
+	for _, name := range []string{"ascii", "binary", "local"} {
+		if transferTypeNames[name] == transferType {
+			fmt.Printf("Using %s mode to transfer files.\n", name)
+			return
+		}
+	}
+}
+
+//切换被动模式和主动模式，没有连接的时候也可以切换，下次连接时生效
+func (this *GoFtpClientCmd) passive() (err error) {
+	this.ActiveMode = !this.ActiveMode
+	if this.FtpClient != nil {
+		this.FtpClient.SetPassive(!this.ActiveMode)
+	}
+	if this.ActiveMode {
+		fmt.Println("Passive mode off.")
 	} else {
-		var bReader = bufio.NewReader(pasvConn)
-		pasvRespData = make([]byte, 0)
-		for {
-			line, err := bReader.ReadBytes('\n')
-			pasvRespData = append(pasvRespData, []byte(line)...)
-			if err == io.EOF {
-				break
-			}
+		fmt.Println("Passive mode on.")
+	}
+	return
+}
+
+//切换IPv4连接的被动模式下是否优先使用EPSV，IPv6连接总是使用EPSV
+func (this *GoFtpClientCmd) epsv4() (err error) {
+	this.PreferEPSV = !this.PreferEPSV
+	if this.FtpClient != nil {
+		this.FtpClient.SetPreferEPSV(this.PreferEPSV)
+	}
+	if this.PreferEPSV {
+		fmt.Println("EPSV on IPv4 on.")
+	} else {
+		fmt.Println("EPSV on IPv4 off.")
+	}
+	return
+}
+
+//通过AUTH TLS把当前的控制连接升级为TLS连接，需要在登录之前执行，
+//这样用户名和密码才不会以明文传输
+func (this *GoFtpClientCmd) ssl() (err error) {
+	if err = this.checkConnected(); err != nil {
+		return
+	}
+	//服务器在FEAT中列出了AUTH机制但是没有TLS的时候就不用尝试了
+	if features, featErr := this.FtpClient.Features(); featErr == nil && features.Has(FEAT_AUTH) {
+		var supported bool
+		for _, authType := range features.AuthTypes() {
+			supported = supported || strings.EqualFold(authType, "TLS")
+		}
+		if !supported {
+			return errors.New("Server does not support AUTH TLS.")
+		}
+	}
+	err = this.FtpClient.AuthTLS()
+	if err == nil {
+		fmt.Println("TLS connection established.")
+	} else if !isProtocolError(err) {
+		//TLS握手失败的时候控制连接已经被关闭了
+		this.reset()
+	}
+	return
+}
+
+//打印服务器通过FEAT命令列出的扩展功能
+func (this *GoFtpClientCmd) features() (err error) {
+	if err = this.checkConnected(); err != nil {
+		return
+	}
+	features, err := this.FtpClient.Features()
+	if err != nil {
+		return
+	}
+	if len(features) == 0 {
+		fmt.Println("Server does not support any extensions.")
+		return
+	}
+	var names = make([]string, 0, len(features))
+	for name := range features {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Println("Features supported by server:")
+	for _, name := range names {
+		fmt.Println(" " + strings.TrimSpace(name+" "+features[name]))
+	}
+	return
+}
+
+//服务器的FEAT回复中没有列出某个功能的时候返回错误，服务器不支持FEAT
+//的时候没法判断，当作支持，让服务器自己回复
+func (this *GoFtpClientCmd) requireFeature(feature string) (err error) {
+	features, featErr := this.FtpClient.Features()
+	if featErr == nil && len(features) > 0 && !features.Has(feature) {
+		err = fmt.Errorf("Server does not support %s.", feature)
+	}
+	return
+}
+
+//没有参数的时候打印控制连接使用的字符集，否则设置字符集，已经连接的
+//时候马上生效
+func (this *GoFtpClientCmd) charset() (err error) {
+	switch len(this.Params) {
+	case 0:
+		var name = "none"
+		if this.Charset != nil {
+			name = this.Charset.Name()
+		}
+		fmt.Printf("Using charset %s for file names.\n", name)
+		fmt.Println("Supported charsets:", strings.Join(CharsetNames(), " "))
+	case 1:
+		charset, lookupErr := LookupCharset(this.Params[0])
+		if lookupErr != nil {
+			return lookupErr
+		}
+		this.Charset = charset
+		if this.Connected {
+			this.FtpClient.SetCharset(charset)
+		}
+		fmt.Printf("Using charset %s for file names.\n", charset.Name())
+	default:
+		this.cmdUsage(this.Name)
+	}
+	return
+}
+
+//交互命令中使用的数据连接保护级别名称
+var protLevelNames = map[string]ProtectionLevel{
+	"clear":   PROT_LEVEL_CLEAR,
+	"private": PROT_LEVEL_PRIVATE,
+}
+
+//没有参数的时候打印当前的数据连接保护级别，否则设置保护级别
+func (this *GoFtpClientCmd) prot() (err error) {
+	if err = this.checkConnected(); err != nil {
+		return
+	}
+	switch len(this.Params) {
+	case 0:
+	case 1:
+		var name = strings.ToLower(this.Params[0])
+		protLevel, ok := protLevelNames[name]
+		if !ok {
+			protLevel = ProtectionLevel(strings.ToUpper(name))
+		}
+		if err = this.FtpClient.Prot(protLevel); err != nil {
+			return
+		}
+	default:
+		this.cmdUsage(this.Name)
+		return
+	}
+	for name, protLevel := range protLevelNames {
+		if protLevel == this.FtpClient.ProtectionLevel() {
+			fmt.Println("Data channel protection level:", name)
 		}
-		pasvConn.Close()
 	}
 	return
 }
 
-func (this *GoFtpClientCmd) disconnect() {
-	this.close()
+//MLST的结果通过控制连接返回，服务器的回复已经打印过了
+func (this *GoFtpClientCmd) mlst() (err error) {
+	if err = this.checkConnected(); err != nil {
+		return
+	}
+	if err = this.requireFeature(FEAT_MLST); err != nil {
+		return
+	}
+	if len(this.Params) > 1 {
+		this.cmdUsage(this.Name)
+		return
+	}
+	var remotePath string
+	if len(this.Params) == 1 {
+		remotePath = this.Params[0]
+	}
+	_, err = this.FtpClient.Mlst(remotePath)
+	return
 }
 
-func (this *GoFtpClientCmd) close() {
-	if this.FtpConn != nil {
-		this.sendCmdRequest([]string{FC_QUIT})
-		this.recvCmdResponse()
+//切换上传文件时是否由服务器生成唯一的文件名
+func (this *GoFtpClientCmd) sunique() (err error) {
+	this.StoreUnique = !this.StoreUnique
+	if this.StoreUnique {
+		fmt.Println("Store unique on.")
+	} else {
+		fmt.Println("Store unique off.")
+	}
+	return
+}
 
-		this.FtpConn = nil
-		this.Name = ""
-		this.Params = nil
-		this.Connected = false
+//设置下一次get或者put开始传输的位置，没有参数的时候清除
+func (this *GoFtpClientCmd) restart() (err error) {
+	if len(this.Params) > 1 {
+		this.cmdUsage(this.Name)
+		return
+	}
+	if len(this.Params) == 0 {
+		this.RestartMarker = 0
+		fmt.Println("restart: offset not specified")
+		return
 	}
+	marker, parseErr := strconv.ParseInt(this.Params[0], 10, 64)
+	if parseErr != nil || marker < 0 {
+		return fmt.Errorf("restart: Invalid offset `%s'", this.Params[0])
+	}
+	this.RestartMarker = marker
+	fmt.Printf("Restarting at %d. Execute get or put to initiate transfer\n", marker)
+	return
+}
+
+//本地文件的路径，相对路径都是相对于本地工作目录的
+func (this *GoFtpClientCmd) localPath(localFile string) string {
+	if filepath.IsAbs(localFile) {
+		return localFile
+	}
+	return filepath.Join(this.LocalWorkDir, localFile)
+}
+
+//打印传输的字节数、用时和速度
+func printTransferStat(n int64, action string, elapsed time.Duration) {
+	var seconds = elapsed.Seconds()
+	var rate = float64(n)
+	if seconds > 0 {
+		rate = float64(n) / seconds
+	}
+	fmt.Printf("%d bytes %s in %.2f secs (%s/s)\n", n, action, seconds, formatBytes(rate))
+}
+
+//把字节数转换为便于阅读的形式
+func formatBytes(n float64) string {
+	var units = []string{"bytes", "KB", "MB", "GB", "TB"}
+	var unitIndex = 0
+	for n >= 1024 && unitIndex < len(units)-1 {
+		n /= 1024
+		unitIndex++
+	}
+	if unitIndex == 0 {
+		return fmt.Sprintf("%.0f %s", n, units[unitIndex])
+	}
+	return fmt.Sprintf("%.2f %s", n, units[unitIndex])
+}
+
+func (this *GoFtpClientCmd) disconnect() (err error) {
+	return this.close()
+}
+
+func (this *GoFtpClientCmd) close() (err error) {
+	if this.FtpClient != nil {
+		err = this.FtpClient.Quit()
+		this.reset()
+	}
+	return
+}
+
+//重置连接状态
+func (this *GoFtpClientCmd) reset() {
+	this.FtpClient = nil
+	this.Name = ""
+	this.Params = nil
+	this.ParamLine = ""
+	this.Connected = false
 }
@@ -0,0 +1,31 @@
+package goftp
+
+import (
+	"os"
+	"time"
+)
+
+//远程文件的类型
+type EntryType int
+
+const (
+	ENTRY_TYPE_FILE EntryType = iota
+	ENTRY_TYPE_DIR
+	ENTRY_TYPE_LINK
+)
+
+//表示远程目录中的一个文件或者文件夹
+//下面除了Name、Type和Raw之外的字段，服务器没有提供的时候都是零值
+type Entry struct {
+	Name     string            //文件名
+	Type     EntryType         //文件类型
+	Size     int64             //文件大小，单位是字节
+	ModTime  time.Time         //最后修改时间
+	Perm     string            //MLSx的perm，表示当前用户对这个文件可以进行的操作，比如`adfrw`
+	Unique   string            //MLSx的unique，服务器上唯一标识这个文件的值
+	UnixMode os.FileMode       //Unix风格的文件权限
+	Owner    string            //文件所有者
+	Target   string            //符号链接指向的路径
+	Facts    map[string]string //MLSx返回的所有信息，键是小写的信息名
+	Raw      string            //服务器返回的原始行
+}
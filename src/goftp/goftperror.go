@@ -0,0 +1,156 @@
+package goftp
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+//可以和errors.Is一起使用的错误值，服务器回复的*ProtocolError
+//会根据回复码匹配下面对应的错误值
+var (
+	ErrNotConnected = errors.New("Not connected.")
+
+	//按照回复码的类别匹配，4xx和5xx
+	ErrTransientNegative = errors.New("transient negative completion reply")
+	ErrPermanentNegative = errors.New("permanent negative completion reply")
+
+	//按照具体的回复码匹配
+	ErrServiceNotAvailable = errors.New("service not available")          //421
+	ErrCannotOpenDataConn  = errors.New("can't open data connection")     //425
+	ErrTransferAborted     = errors.New("transfer aborted")               //426
+	ErrFileBusy            = errors.New("file busy")                      //450
+	ErrLocalError          = errors.New("local error in processing")      //451
+	ErrInsufficientStorage = errors.New("insufficient storage space")     //452
+	ErrSyntax              = errors.New("syntax error")                   //500, 501
+	ErrNotImplemented      = errors.New("command not implemented")        //502, 504
+	ErrBadSequence         = errors.New("bad sequence of commands")       //503
+	ErrNotLoggedIn         = errors.New("not logged in")                  //530
+	ErrNeedAccount         = errors.New("need account for storing files") //532
+	ErrFileUnavailable     = errors.New("file unavailable")               //550
+	ErrPageTypeUnknown     = errors.New("page type unknown")              //551
+	ErrExceededStorage     = errors.New("exceeded storage allocation")    //552
+	ErrFileNameNotAllowed  = errors.New("file name not allowed")          //553
+)
+
+//回复码和错误值的对应关系
+var replyCodeErrors = map[int]error{
+	FC_RESP_CODE_SERVICE_NOT_AVAILABLE:  ErrServiceNotAvailable,
+	FC_RESP_CODE_CANNOT_OPEN_DATA_CONN:  ErrCannotOpenDataConn,
+	FC_RESP_CODE_TRANSFER_ABORTED:       ErrTransferAborted,
+	FC_RESP_CODE_FILE_BUSY:              ErrFileBusy,
+	FC_RESP_CODE_LOCAL_ERROR:            ErrLocalError,
+	FC_RESP_CODE_INSUFFICIENT_STORAGE:   ErrInsufficientStorage,
+	FC_RESP_CODE_SYNTAX_ERROR:           ErrSyntax,
+	FC_RESP_CODE_SYNTAX_ERROR_IN_PARAMS: ErrSyntax,
+	FC_RESP_CODE_NOT_IMPLEMENTED:        ErrNotImplemented,
+	FC_RESP_CODE_PARAM_NOT_IMPLEMENTED:  ErrNotImplemented,
+	FC_RESP_CODE_BAD_SEQUENCE:           ErrBadSequence,
+	FC_RESP_CODE_NOT_LOGGED_IN:          ErrNotLoggedIn,
+	FC_RESP_CODE_NEED_ACCOUNT_FOR_STORE: ErrNeedAccount,
+	FC_RESP_CODE_FILE_UNAVAILABLE:       ErrFileUnavailable,
+	FC_RESP_CODE_PAGE_TYPE_UNKNOWN:      ErrPageTypeUnknown,
+	FC_RESP_CODE_EXCEEDED_STORAGE:       ErrExceededStorage,
+	FC_RESP_CODE_FILE_NAME_NOT_ALLOWED:  ErrFileNameNotAllowed,
+}
+
+//服务器返回了错误的或者不符合预期的回复
+type ProtocolError struct {
+	Cmd  string //触发这个回复的命令，PASS命令的密码不会被记录
+	Code int    //回复码
+	Msg  string //回复的文本内容
+}
+
+func (this *ProtocolError) Error() string {
+	var text = strconv.Itoa(this.Code) + " " + strings.Replace(this.Msg, "\n", " ", -1)
+	if this.Cmd == "" {
+		return text
+	}
+	return this.Cmd + ": " + text
+}
+
+//让errors.Is可以按照回复码和回复码的类别来匹配错误值
+func (this *ProtocolError) Is(target error) bool {
+	if target == ErrTransientNegative {
+		return IsTransientNegative(this.Code)
+	}
+	if target == ErrPermanentNegative {
+		return IsPermanentNegative(this.Code)
+	}
+	return replyCodeErrors[this.Code] == target
+}
+
+//判断是否为服务器回复的错误
+func isProtocolError(err error) bool {
+	var protocolErr *ProtocolError
+	return errors.As(err, &protocolErr)
+}
+
+//服务器关闭了控制连接，后面的命令都不能再执行
+func isConnectionLost(err error) bool {
+	return errors.Is(err, ErrServiceNotAvailable) || errors.Is(err, io.EOF)
+}
+
+//根据命令和服务器的回复生成错误
+func newProtocolError(ftpParams []string, reply Reply) *ProtocolError {
+	var cmd = strings.Join(ftpParams, " ")
+	if len(ftpParams) > 0 && strings.ToUpper(ftpParams[0]) == FC_PASS {
+		cmd = FC_PASS + " ****"
+	}
+	return &ProtocolError{
+		Cmd:  cmd,
+		Code: reply.Code,
+		Msg:  reply.Message(),
+	}
+}
+
+//1xx 肯定的初步回复，命令已经开始执行，还会有下一个回复
+func IsPositivePreliminary(code int) bool {
+	return code >= 100 && code < 200
+}
+
+//2xx 肯定的完成回复，命令已经成功完成
+func IsPositiveCompletion(code int) bool {
+	return code >= 200 && code < 300
+}
+
+//3xx 肯定的中间回复，命令已经接受，还需要发送下一个命令
+func IsPositiveIntermediate(code int) bool {
+	return code >= 300 && code < 400
+}
+
+//4xx 暂时的否定完成回复，命令没有执行，稍后可以重试
+func IsTransientNegative(code int) bool {
+	return code >= 400 && code < 500
+}
+
+//5xx 永久的否定完成回复，命令没有执行，重试也不会成功
+func IsPermanentNegative(code int) bool {
+	return code >= 500 && code < 600
+}
+
+//回复码是否属于1xx
+func (this Reply) IsPositivePreliminary() bool {
+	return IsPositivePreliminary(this.Code)
+}
+
+//回复码是否属于2xx
+func (this Reply) IsPositiveCompletion() bool {
+	return IsPositiveCompletion(this.Code)
+}
+
+//回复码是否属于3xx
+func (this Reply) IsPositiveIntermediate() bool {
+	return IsPositiveIntermediate(this.Code)
+}
+
+//回复码是否属于4xx
+func (this Reply) IsTransientNegative() bool {
+	return IsTransientNegative(this.Code)
+}
+
+//回复码是否属于5xx
+func (this Reply) IsPermanentNegative() bool {
+	return IsPermanentNegative(this.Code)
+}
@@ -0,0 +1,138 @@
+package goftp
+
+import (
+	"strings"
+)
+
+//服务器通过FEAT命令列出的扩展功能，定义见RFC 2389，键是大写的功能名，
+//值是功能名后面的参数，比如`MLST type*;size*;`的参数是`type*;size*;`，
+//同一个功能出现多次的时候，比如`AUTH TLS`和`AUTH SSL`，参数用分号连接
+type Features map[string]string
+
+//常用的扩展功能名
+const (
+	FEAT_MLST = "MLST"
+	FEAT_UTF8 = "UTF8"
+	FEAT_REST = "REST"
+	FEAT_EPSV = "EPSV"
+	FEAT_SIZE = "SIZE"
+	FEAT_MDTM = "MDTM"
+	FEAT_MFMT = "MFMT"
+	FEAT_AUTH = "AUTH"
+	FEAT_HASH = "HASH"
+)
+
+//服务器是否支持某个扩展功能
+func (this Features) Has(feature string) bool {
+	_, ok := this[strings.ToUpper(feature)]
+	return ok
+}
+
+//服务器是否支持流模式下的REST，也就是断点续传
+func (this Features) RestStream() bool {
+	return strings.EqualFold(this[FEAT_REST], "STREAM")
+}
+
+//服务器支持的AUTH机制，比如TLS、SSL
+func (this Features) AuthTypes() (authTypes []string) {
+	authTypes, _ = splitFeatureParams(this[FEAT_AUTH])
+	return
+}
+
+//MLSx支持的信息和当前选择返回的信息
+func (this Features) MlstFacts() (facts []string, selected []string) {
+	return splitFeatureParams(this[FEAT_MLST])
+}
+
+//HASH命令支持的算法和当前选择的算法，定义见draft-bryan-ftpext-hash
+func (this Features) HashAlgorithms() (algorithms []string, selected string) {
+	algorithms, selectedAlgorithms := splitFeatureParams(this[FEAT_HASH])
+	if len(selectedAlgorithms) > 0 {
+		selected = selectedAlgorithms[0]
+	}
+	return
+}
+
+//拆分用分号分隔的功能参数，后面带`*`的表示当前选中的
+func splitFeatureParams(params string) (values []string, selected []string) {
+	for _, value := range strings.Split(params, ";") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if strings.HasSuffix(value, "*") {
+			value = strings.TrimSuffix(value, "*")
+			selected = append(selected, value)
+		}
+		values = append(values, value)
+	}
+	return
+}
+
+//服务器支持的扩展功能，FEAT命令只在第一次需要的时候和登录之后发送
+func (this *Client) Features() (features Features, err error) {
+	return this.loadFeatures()
+}
+
+//服务器是否支持某个扩展功能，获取失败的时候当作不支持
+func (this *Client) HasFeature(feature string) bool {
+	features, _ := this.loadFeatures()
+	return features.Has(feature)
+}
+
+//发送FEAT命令获取服务器支持的扩展功能，只在第一次需要的时候发送，
+//服务器不支持FEAT命令的时候当作没有扩展功能
+func (this *Client) loadFeatures() (features Features, err error) {
+	if this.features != nil {
+		return this.features, nil
+	}
+	reply, err := this.cmd(FC_FEAT)
+	if err != nil {
+		if !isProtocolError(err) {
+			return
+		}
+		err = nil
+	}
+	this.features = parseFeatures(reply)
+	//服务器列出了EPSV的时候被动模式下优先使用EPSV
+	if this.features.Has(FEAT_EPSV) {
+		this.useEPSV = true
+	}
+	return this.features, nil
+}
+
+//登录之后服务器支持的功能可能和登录之前不同，所以重新发送FEAT命令
+func (this *Client) refreshFeatures() (err error) {
+	this.features = nil
+	_, err = this.loadFeatures()
+	return
+}
+
+//解析FEAT命令的211回复，首行和末行之外的每一行是一个功能，以空格开头
+//
+//	211-Features:
+//	 MLST type*;size*;modify*;
+//	 UTF8
+//	211 End
+func parseFeatures(reply Reply) (features Features) {
+	features = make(Features)
+	if reply.Code != FC_RESP_CODE_SYSTEM_STATUS || len(reply.Lines) < 2 {
+		return
+	}
+	for _, line := range reply.Lines[1 : len(reply.Lines)-1] {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		var name, params = line, ""
+		if index := strings.Index(line, " "); index != -1 {
+			name, params = line[:index], strings.TrimSpace(line[index+1:])
+		}
+		name = strings.ToUpper(name)
+		if features[name] != "" && params != "" {
+			params = features[name] + ";" + params
+		}
+		features[name] = params
+	}
+	return
+}
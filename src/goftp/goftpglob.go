@@ -0,0 +1,113 @@
+package goftp
+
+import (
+	"errors"
+	"path"
+	"sort"
+	"strings"
+)
+
+//是否包含通配符，通配符的语法和path.Match相同
+func hasGlobMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[\\")
+}
+
+//在远程服务器上展开通配符，返回匹配的路径，按字母顺序排列。pattern中
+//每一级都可以有通配符，语法和path.Match相同，和shell一样`.`开头的文件
+//只有在模式也以`.`开头的时候才匹配。不依赖服务器对NLST参数的通配符扩展，
+//而是获取目录下的文件名之后在客户端匹配，服务器不支持通配符的时候也可以
+//使用。没有通配符的pattern原样返回，不检查文件是否存在
+func (this *Client) Glob(pattern string) (matches []string, err error) {
+	if !hasGlobMeta(pattern) {
+		return []string{pattern}, nil
+	}
+	if _, err = path.Match(pattern, ""); err != nil {
+		return
+	}
+	dir, filePattern := path.Split(pattern)
+	if dir != "/" {
+		dir = strings.TrimSuffix(dir, "/")
+	}
+	var dirs = []string{dir}
+	if hasGlobMeta(dir) {
+		if dirs, err = this.globDirs(dir); err != nil {
+			return
+		}
+	}
+	for _, dir := range dirs {
+		names, listErr := this.globNames(dir)
+		if listErr != nil {
+			//只有一个目录的时候报告错误，否则跳过不能列出的目录
+			if len(dirs) == 1 {
+				return nil, listErr
+			}
+			continue
+		}
+		for _, name := range names {
+			if globMatch(filePattern, name) {
+				matches = append(matches, path.Join(dir, name))
+			}
+		}
+	}
+	sort.Strings(matches)
+	return
+}
+
+//展开目录部分的通配符，只返回目录和符号链接
+func (this *Client) globDirs(pattern string) (dirs []string, err error) {
+	parents, err := this.Glob(path.Dir(pattern))
+	if err != nil {
+		return
+	}
+	var dirPattern = path.Base(pattern)
+	for _, parent := range parents {
+		entries, listErr := this.List(parent)
+		var parseErr *ListParseError
+		if listErr != nil && !errors.As(listErr, &parseErr) {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.Type != ENTRY_TYPE_FILE && globMatch(dirPattern, entry.Name) {
+				dirs = append(dirs, path.Join(parent, entry.Name))
+			}
+		}
+	}
+	sort.Strings(dirs)
+	return
+}
+
+//获取目录下的文件名，服务器支持MLST的时候使用MLSD，否则使用NLST，
+//有的服务器NLST返回的名字前面带着目录，这里只保留文件名
+func (this *Client) globNames(dir string) (names []string, err error) {
+	if this.HasFeature(FEAT_MLST) {
+		entries, mlsdErr := this.Mlsd(dir)
+		if mlsdErr == nil {
+			for _, entry := range entries {
+				names = append(names, entry.Name)
+			}
+			return
+		}
+		if !errors.Is(mlsdErr, ErrSyntax) && !errors.Is(mlsdErr, ErrNotImplemented) {
+			return nil, mlsdErr
+		}
+	}
+	lines, err := this.NameList(dir)
+	if err != nil {
+		return
+	}
+	for _, line := range lines {
+		if name := path.Base(line); name != "." && name != ".." {
+			names = append(names, name)
+		}
+	}
+	return
+}
+
+//和path.Match一样，但是`.`开头的文件名只有模式也以`.`开头的时候才匹配
+func globMatch(pattern string, name string) bool {
+	if strings.HasPrefix(name, ".") && !strings.HasPrefix(pattern, ".") {
+		return false
+	}
+	matched, _ := path.Match(pattern, name)
+	return matched
+}
@@ -0,0 +1,135 @@
+package goftp
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+)
+
+//HASH命令支持的算法名，定义见draft-bryan-ftpext-hash
+const (
+	HASH_ALGORITHM_MD5     = "MD5"
+	HASH_ALGORITHM_SHA_1   = "SHA-1"
+	HASH_ALGORITHM_SHA_256 = "SHA-256"
+	HASH_ALGORITHM_SHA_512 = "SHA-512"
+)
+
+//本地可以计算的算法，按照校验时优先选择的顺序排列
+var hashAlgorithms = []struct {
+	name    string
+	newHash func() hash.Hash
+}{
+	{HASH_ALGORITHM_SHA_256, sha256.New},
+	{HASH_ALGORITHM_SHA_512, sha512.New},
+	{HASH_ALGORITHM_SHA_1, sha1.New},
+	{HASH_ALGORITHM_MD5, md5.New},
+}
+
+var ErrHashNotSupported = errors.New("Server does not support HASH")
+
+//获取远程文件的哈希值，使用服务器当前选择的算法，返回的哈希值是小写的
+//十六进制字符串
+//
+//	213 SHA-256 0-49 169cd22282da7f147cb491e559e9dd filename
+func (this *Client) Hash(path string) (algorithm string, sum string, err error) {
+	reply, err := this.cmdOK(FC_HASH, path)
+	if err != nil {
+		return
+	}
+	var fields = strings.Fields(reply.Message())
+	if len(fields) < 3 {
+		err = newProtocolError([]string{FC_HASH, path}, reply)
+		return
+	}
+	return strings.ToUpper(fields[0]), strings.ToLower(fields[2]), nil
+}
+
+//通过`OPTS HASH`选择HASH命令使用的算法
+func (this *Client) SetHashAlgorithm(algorithm string) (err error) {
+	if _, err = this.cmdOK(FC_OPTS, FEAT_HASH, algorithm); err != nil {
+		return
+	}
+	//FEAT中标记的当前算法已经变了
+	return this.refreshFeatures()
+}
+
+//比较远程文件和本地文件的哈希值，服务器当前选择的算法本地不能计算的时候，
+//从服务器支持的算法中选择一个本地可以计算的
+func (this *Client) VerifyHash(remotePath string, localPath string) (err error) {
+	features, err := this.loadFeatures()
+	if err != nil {
+		return
+	}
+	if !features.Has(FEAT_HASH) {
+		return ErrHashNotSupported
+	}
+	algorithms, selected := features.HashAlgorithms()
+	if findHashAlgorithm(selected) == nil {
+		for _, candidate := range hashAlgorithms {
+			if containsFold(algorithms, candidate.name) {
+				if err = this.SetHashAlgorithm(candidate.name); err != nil {
+					return
+				}
+				break
+			}
+		}
+	}
+	algorithm, remoteSum, err := this.Hash(remotePath)
+	if err != nil {
+		return
+	}
+	localSum, err := hashLocalFile(localPath, algorithm)
+	if err != nil {
+		return
+	}
+	if localSum != remoteSum {
+		err = fmt.Errorf("%s mismatch: local %s, remote %s", algorithm, localSum, remoteSum)
+	}
+	return
+}
+
+//查找本地可以计算的算法，不区分大小写
+func findHashAlgorithm(name string) func() hash.Hash {
+	for _, algorithm := range hashAlgorithms {
+		if strings.EqualFold(algorithm.name, name) {
+			return algorithm.newHash
+		}
+	}
+	return nil
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+//用指定的算法计算本地文件的哈希值
+func hashLocalFile(localPath string, algorithm string) (sum string, err error) {
+	var newHash = findHashAlgorithm(algorithm)
+	if newHash == nil {
+		err = fmt.Errorf("Unsupported hash algorithm `%s'", algorithm)
+		return
+	}
+	localFile, err := os.Open(localPath)
+	if err != nil {
+		return
+	}
+	defer localFile.Close()
+	var h = newHash()
+	if _, err = io.Copy(h, localFile); err != nil {
+		return
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -14,6 +14,47 @@ var FTP_CLIENT_CMD_HELP = map[string]string{
 	FCC_LCD:           "change local working directory",
 	FCC_OPEN:          "connect to remote ftp server",
 	FCC_USER:          "send new user information",
+	FCC_GET:           "receive file",
+	FCC_RECV:          "receive file",
+	FCC_PUT:           "send one file",
+	FCC_SEND:          "send one file",
+	FCC_APPEND:        "append to a file",
+	FCC_SUNIQUE:       "toggle store unique on remote machine",
+	FCC_ASCII:         "set ascii transfer type",
+	FCC_BINARY:        "set binary transfer type",
+	FCC_IMAGE:         "set binary transfer type",
+	FCC_TYPE:          "set file transfer type",
+	FCC_PASSIVE:       "toggle passive transfer mode",
+	FCC_EPSV4:         "toggle use of EPSV on IPv4 ftp",
+	FCC_SSL:           "secure the control connection with AUTH TLS",
+	FCC_PROT:          "set data channel protection level",
+	FCC_MLSD:          "list contents of remote path in a machine parsable form",
+	FCC_MLST:          "list remote path in a machine parsable form",
+	FCC_FEATURES:      "show features supported by remote system",
+	FCC_CHARSET:       "set charset used for remote file names",
+	FCC_REGET:         "get file restarting at end of local file",
+	FCC_REPUT:         "put file restarting at end of remote file",
+	FCC_RESTART:       "restart file transfer at bytecount",
+	FCC_MGET:          "get multiple files",
+	FCC_MPUT:          "send multiple files",
+	FCC_MDELETE:       "delete multiple files",
+	FCC_MDIR:          "list contents of multiple remote directories",
+	FCC_GLOB:          "toggle metacharacter expansion of file names",
+	FCC_PROMPT:        "force interactive prompting on multiple commands",
+	FCC_MIRROR:        "synchronize local and remote directory trees",
+	FCC_CDUP:          "change remote working directory to parent directory",
+	FCC_MKDIR:         "make directory on the remote machine",
+	FCC_RMDIR:         "remove directory on the remote machine",
+	FCC_DELETE:        "delete remote file",
+	FCC_RENAME:        "rename file",
+	FCC_CHMOD:         "change file permissions of remote file",
+	FCC_SIZE:          "show size of remote file",
+	FCC_MODTIME:       "show or set last modification time of remote file",
+	FCC_LITERAL:       "send arbitrary ftp command",
+	FCC_QUOTE:         "send arbitrary ftp command",
+	FCC_SITE:          "send site specific command to remote server",
+	FCC_HASH:          "toggle printing `#' for each buffer transferred",
+	FCC_PROGRESS:      "toggle transfer progress meter",
 	FCC_USAGE:         "show usage of ftp command",
 }
 
@@ -25,8 +66,49 @@ var FTP_CLIENT_CMD_USAGE = map[string]string{
 	FCC_CD:            "cd remote_dir",
 	FCC_LS:            "ls [remote_dir|remote_file] [local_output_file]",
 	FCC_LCD:           "lcd [local_directory]",
-	FCC_OPEN:          "open remote_host [port]",
+	FCC_OPEN:          "open [-implicit] [ftp://|ftps://]remote_host [port]",
 	FCC_USER:          "user username [password] [account]",
+	FCC_GET:           "get [-n segments] [-verify] [-p] [-r [-L]] remote_file [local_file]",
+	FCC_RECV:          "recv [-n segments] [-verify] [-p] [-r [-L]] remote_file [local_file]",
+	FCC_PUT:           "put [-p] [-r [-L]] local_file [remote_file]",
+	FCC_SEND:          "send [-p] [-r [-L]] local_file [remote_file]",
+	FCC_APPEND:        "append local_file [remote_file]",
+	FCC_SUNIQUE:       "sunique",
+	FCC_ASCII:         "ascii",
+	FCC_BINARY:        "binary",
+	FCC_IMAGE:         "image",
+	FCC_TYPE:          "type [ascii|binary|image|local]",
+	FCC_PASSIVE:       "passive",
+	FCC_EPSV4:         "epsv4",
+	FCC_SSL:           "ssl",
+	FCC_PROT:          "prot [clear|private]",
+	FCC_MLSD:          "mlsd [remote_dir] [local_output_file]",
+	FCC_MLST:          "mlst [remote_path]",
+	FCC_FEATURES:      "features",
+	FCC_CHARSET:       "charset [utf-8|gbk|gb18030|big5|shift_jis|iso-8859-1]",
+	FCC_REGET:         "reget [-verify] [-p] [-r [-L]] remote_file [local_file]",
+	FCC_REPUT:         "reput [-p] [-r [-L]] local_file [remote_file]",
+	FCC_RESTART:       "restart [bytecount]",
+	FCC_MGET:          "mget remote_file [...]",
+	FCC_MPUT:          "mput local_file [...]",
+	FCC_MDELETE:       "mdelete remote_file [...]",
+	FCC_MDIR:          "mdir remote_dir [...] [local_file|-]",
+	FCC_GLOB:          "glob",
+	FCC_PROMPT:        "prompt",
+	FCC_MIRROR:        "mirror [-R] [-delete] [-dry-run] [-include pattern] [-exclude pattern] [-state file] local_dir remote_dir",
+	FCC_CDUP:          "cdup",
+	FCC_MKDIR:         "mkdir remote_dir",
+	FCC_RMDIR:         "rmdir remote_dir",
+	FCC_DELETE:        "delete remote_file",
+	FCC_RENAME:        "rename from_name to_name",
+	FCC_CHMOD:         "chmod mode remote_file",
+	FCC_SIZE:          "size remote_file",
+	FCC_MODTIME:       "modtime remote_file [YYYYMMDDHHMMSS]",
+	FCC_LITERAL:       "literal argument [...]",
+	FCC_QUOTE:         "quote argument [...]",
+	FCC_SITE:          "site argument [...]",
+	FCC_HASH:          "hash [size]",
+	FCC_PROGRESS:      "progress",
 }
 
 type GoFtpClientHelp struct {
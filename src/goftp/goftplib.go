@@ -0,0 +1,713 @@
+package goftp
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//建立连接时使用的选项，零值表示使用默认设置
+type DialOptions struct {
+	Timeout  time.Duration //连接ftp服务器的超时时间，为0时使用DIAL_FTP_SERVER_TIMEOUT_SECONDS
+	ReplyLog io.Writer     //如果不为nil，服务器的每一条回复都会原样写到这里，交互模式下就是标准输出
+
+	ActiveMode          bool //使用主动模式建立数据连接，默认使用被动模式
+	SkipActivePeerCheck bool //主动模式下不检查连接过来的地址是否和控制连接的服务器地址相同
+
+	PreferEPSV     bool           //被动模式下总是优先使用EPSV，否则只在IPv6连接、FEAT列出了EPSV或者PASV失败的时候使用
+	PasvAddrPolicy PasvAddrPolicy //如何使用PASV回复中的地址，默认为PASV_ADDR_POLICY_AUTO
+
+	ExplicitTLS bool        //连接成功后马上通过AUTH TLS把控制连接升级为TLS连接
+	ImplicitTLS bool        //隐式FTPS，从连接建立开始就使用TLS，默认端口为990
+	TLSConfig   *tls.Config //TLS连接使用的配置，为nil时使用默认配置
+
+	CAFile        string        //PEM格式的CA证书文件，用来验证服务器证书，为空时使用系统的CA证书
+	CertFile      string        //PEM格式的客户端证书文件，服务器要求客户端证书的时候使用
+	KeyFile       string        //客户端证书的私钥文件，为空时从CertFile中读取
+	KnownHosts    *KnownHosts   //不为nil时，没有通过CA验证的服务器证书按照记录的指纹来信任
+	TrustCallback TrustCallback //服务器证书的指纹未知或者发生变化时决定是否信任，为nil时只信任第一次连接的证书
+
+	Charset Charset //控制连接上路径和回复使用的字符集，为nil时原样发送和接收，服务器支持UTF8的时候会发送OPTS UTF8 ON
+}
+
+//被动模式下如何使用PASV回复中的地址
+type PasvAddrPolicy int
+
+const (
+	PASV_ADDR_POLICY_AUTO             PasvAddrPolicy = iota //回复中的地址是内网地址或者和控制连接的服务器地址不同时，使用控制连接的服务器地址
+	PASV_ADDR_POLICY_TRUST_REPLY                            //总是使用回复中的地址
+	PASV_ADDR_POLICY_USE_CONTROL_HOST                       //总是使用控制连接的服务器地址
+)
+
+//PASV回复中的h1,h2,h3,h4,p1,p2
+var pasvReplyRegexp = regexp.MustCompile(`(\d+),(\d+),(\d+),(\d+),(\d+),(\d+)`)
+
+//可供其他程序直接使用的ftp客户端，所有的方法都通过返回值和错误
+//来报告结果，不会向标准输出打印任何东西
+type Client struct {
+	host    string        //连接时使用的主机名
+	conn    net.Conn      //控制连接
+	reader  *bufio.Reader //控制连接上的带缓冲读取器，用来按行读取回复
+	opts    DialOptions   //建立连接时的选项
+	welcome Reply         //服务器的欢迎信息
+	timeout time.Duration //数据连接的超时时间
+
+	transferType TransferType //设置的文件传输类型
+	serverType   TransferType //已经通过TYPE命令告诉服务器的传输类型，为空表示还没有发送过
+	passive      bool         //是否使用被动模式建立数据连接
+	useEPSV      bool         //被动模式下是否使用EPSV代替PASV
+
+	tlsConfig       *tls.Config     //控制连接升级为TLS连接之后使用的配置，为nil表示没有使用TLS
+	protLevel       ProtectionLevel //设置的数据连接保护级别
+	serverProtLevel ProtectionLevel //已经通过PROT命令告诉服务器的保护级别
+	pbszSent        bool            //是否已经发送过PBSZ命令
+
+	features Features //FEAT命令返回的扩展功能，为nil表示还没有获取
+	charset  Charset  //控制连接使用的字符集，为nil表示不转换
+
+	username string //登录使用的用户名、密码和账户，Clone建立新的控制连接时使用
+	password string
+	account  string
+}
+
+//连接到addr所指定的ftp服务器，addr的格式为[scheme://]host[:port]，其中
+//scheme可以是ftp或者ftps，ftps表示隐式FTPS。没有指定端口号时使用默认的
+//21端口，隐式FTPS使用990端口。一个主机名可能有多个ip地址，依次尝试连接，
+//连接成功就不再尝试下一个ip地址
+func Dial(addr string, opts *DialOptions) (client *Client, err error) {
+	var options DialOptions
+	if opts != nil {
+		options = *opts
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = time.Duration(DIAL_FTP_SERVER_TIMEOUT_SECONDS) * time.Second
+	}
+
+	addr, implicitTLS, err := splitScheme(addr)
+	if err != nil {
+		return
+	}
+	options.ImplicitTLS = options.ImplicitTLS || implicitTLS
+	var defaultPort = FTP_SERVER_DEFAULT_LISTENING_PORT
+	if options.ImplicitTLS {
+		defaultPort = FTPS_SERVER_DEFAULT_LISTENING_PORT
+	}
+	host, port, err := splitHostPort(addr, defaultPort)
+	if err != nil {
+		return
+	}
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		err = fmt.Errorf("Can't lookup host `%s'", host)
+		return
+	}
+	var conn net.Conn
+	for _, ip := range ips {
+		conn, err = net.DialTimeout("tcp", net.JoinHostPort(ip.String(), port), options.Timeout)
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return
+	}
+
+	client = &Client{
+		host:    host,
+		conn:    conn,
+		reader:  bufio.NewReader(conn),
+		opts:    options,
+		timeout: options.Timeout,
+
+		transferType: TRANSFER_TYPE_BINARY,
+		passive:      !options.ActiveMode,
+		charset:      options.Charset,
+	}
+	//IPv6连接只能使用EPSV，PASV的回复中只能表示IPv4地址
+	client.useEPSV = options.PreferEPSV || client.isIPv6()
+	//隐式FTPS在服务器发送欢迎信息之前就要进行TLS握手
+	if options.ImplicitTLS {
+		if err = client.startTLS(); err != nil {
+			client = nil
+			return
+		}
+	}
+	//连接成功后服务器会先发送欢迎信息，有的服务器会先回复120，
+	//表示稍后才能提供服务
+	reply, err := client.readReply()
+	for err == nil && reply.Code == FC_RESP_CODE_SERVICE_READY_LATER {
+		reply, err = client.readReply()
+	}
+	if err == nil && reply.Code != FC_RESP_CODE_SERVICE_READY {
+		err = newProtocolError(nil, reply)
+	}
+	if err == nil && options.ExplicitTLS && !options.ImplicitTLS {
+		err = client.AuthTLS()
+	}
+	if err != nil {
+		//TLS握手失败的时候startTLS已经关闭了连接
+		client.Close()
+		client = nil
+		return
+	}
+	client.welcome = reply
+	return
+}
+
+//去掉地址前面的ftp://或者ftps://，ftps表示隐式FTPS
+func splitScheme(addr string) (host string, implicitTLS bool, err error) {
+	var index = strings.Index(addr, "://")
+	if index == -1 {
+		return addr, false, nil
+	}
+	switch strings.ToLower(addr[:index]) {
+	case "ftp":
+	case "ftps":
+		implicitTLS = true
+	default:
+		err = fmt.Errorf("Unsupported scheme `%s'", addr[:index])
+		return
+	}
+	host = strings.TrimSuffix(addr[index+len("://"):], "/")
+	return
+}
+
+//把host[:port]格式的地址拆分为主机名和端口号，没有端口号的时候
+//使用defaultPort
+func splitHostPort(addr string, defaultPort int) (host string, port string, err error) {
+	host, port, err = net.SplitHostPort(addr)
+	if err != nil {
+		//IPv6地址可能带有方括号
+		host = strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+		port = strconv.Itoa(defaultPort)
+		err = nil
+	}
+	if host == "" {
+		err = errors.New("No host specified")
+	}
+	return
+}
+
+//服务器的欢迎信息
+func (this *Client) Welcome() Reply {
+	return this.welcome
+}
+
+//控制连接的远程地址，连接已经关闭的时候返回nil
+func (this *Client) RemoteAddr() net.Addr {
+	if this.conn == nil {
+		return nil
+	}
+	return this.conn.RemoteAddr()
+}
+
+//控制连接的远程IP地址，连接已经关闭的时候返回ErrNotConnected
+func (this *Client) remoteIP() (ip net.IP, err error) {
+	if this.conn == nil {
+		return nil, ErrNotConnected
+	}
+	return this.conn.RemoteAddr().(*net.TCPAddr).IP, nil
+}
+
+//发送一条命令
+func (this *Client) sendCmd(ftpParams ...string) (err error) {
+	if this.conn == nil {
+		return ErrNotConnected
+	}
+	var sendData = strings.Join(ftpParams, " ")
+	if this.charset != nil {
+		if sendData, err = this.charset.Encode(sendData); err != nil {
+			return
+		}
+	}
+	_, err = this.conn.Write([]byte(sendData + FC_REQUEST_SUFFIX))
+	return
+}
+
+//读取一条完整的服务器回复
+func (this *Client) readReply() (reply Reply, err error) {
+	if this.conn == nil {
+		err = ErrNotConnected
+		return
+	}
+	reply, err = readReply(this.reader)
+	if err == nil {
+		for i, line := range reply.Lines {
+			reply.Lines[i] = this.decode(line)
+		}
+	}
+	if err == nil && this.opts.ReplyLog != nil {
+		fmt.Fprintln(this.opts.ReplyLog, reply.String())
+	}
+	return
+}
+
+//发送一条命令并读取服务器的回复，回复码为4xx或5xx的时候返回*ProtocolError
+func (this *Client) cmd(ftpParams ...string) (reply Reply, err error) {
+	if err = this.sendCmd(ftpParams...); err != nil {
+		return
+	}
+	reply, err = this.readReply()
+	if err == nil && reply.Code >= 400 {
+		err = newProtocolError(ftpParams, reply)
+	}
+	return
+}
+
+//发送一条命令并读取服务器的回复，回复码不是2xx的时候返回*ProtocolError
+func (this *Client) cmdOK(ftpParams ...string) (reply Reply, err error) {
+	reply, err = this.cmd(ftpParams...)
+	if err == nil && !reply.IsPositiveCompletion() {
+		err = newProtocolError(ftpParams, reply)
+	}
+	return
+}
+
+//发送一条任意的命令并读取服务器的回复，用于客户端还没有封装的命令，
+//回复码为4xx或5xx的时候返回*ProtocolError。回复是1xx的时候继续读取最终
+//的回复，但是不会建立数据连接，所以LIST、RETR这类命令会失败
+func (this *Client) Raw(cmd string, args ...string) (reply Reply, err error) {
+	var ftpParams = append([]string{cmd}, args...)
+	if reply, err = this.cmd(ftpParams...); err != nil {
+		return
+	}
+	if reply.IsPositivePreliminary() {
+		reply, err = this.readReply()
+		if err == nil && reply.Code >= 400 {
+			err = newProtocolError(ftpParams, reply)
+		}
+	}
+	//服务器的传输类型可能已经改变，下一次传输之前重新发送TYPE
+	if strings.EqualFold(cmd, FC_TYPE) {
+		this.serverType = ""
+	}
+	return
+}
+
+//使用用户名和密码登录，如果服务器在USER命令之后就接受了登录，
+//那么不再发送密码
+func (this *Client) Login(username string, password string) (err error) {
+	this.username, this.password, this.account = username, password, ""
+	reply, err := this.cmd(FC_USER, username)
+	if err != nil {
+		return
+	}
+	if reply.Code == FC_RESP_CODE_LOGGED_IN {
+		return this.afterLogin()
+	}
+	if reply.Code != FC_RESP_CODE_NEED_PASSWORD {
+		err = newProtocolError([]string{FC_USER, username}, reply)
+		return
+	}
+	reply, err = this.cmd(FC_PASS, password)
+	if err == nil && reply.Code != FC_RESP_CODE_LOGGED_IN && reply.Code != FC_RESP_CODE_NEED_ACCOUNT {
+		err = newProtocolError([]string{FC_PASS}, reply)
+	}
+	if err == nil && reply.Code == FC_RESP_CODE_LOGGED_IN {
+		err = this.afterLogin()
+	}
+	return
+}
+
+//发送账户信息，部分服务器在登录后还需要账户信息
+func (this *Client) Account(account string) (err error) {
+	if _, err = this.cmdOK(FC_ACCT, account); err != nil {
+		return
+	}
+	this.account = account
+	return this.afterLogin()
+}
+
+//登录之后重新获取服务器支持的功能，服务器支持UTF8并且没有指定其他字符集
+//的时候打开UTF-8，有的服务器(比如Serv-U和IIS)需要OPTS UTF8 ON才会使用UTF-8
+func (this *Client) afterLogin() (err error) {
+	if err = this.refreshFeatures(); err != nil {
+		return
+	}
+	if this.features.Has(FEAT_UTF8) && (this.charset == nil || this.charset.Name() == "utf-8") {
+		if _, err = this.cmd(FC_OPTS, FEAT_UTF8, "ON"); isProtocolError(err) {
+			err = nil
+		}
+	}
+	return
+}
+
+//获取当前所在的远程目录
+func (this *Client) Pwd() (dir string, err error) {
+	reply, err := this.cmdOK(FC_PWD)
+	if err != nil {
+		return
+	}
+	dir, ok := parsePathReply(reply)
+	if !ok {
+		err = newProtocolError([]string{FC_PWD}, reply)
+	}
+	return
+}
+
+//解析PWD和MKD的257回复中的目录名
+func parsePathReply(reply Reply) (dir string, ok bool) {
+	var msg = reply.Message()
+	//回复的格式为 257 "dir" ...，目录名中的双引号用两个双引号表示
+	var startIndex = strings.Index(msg, "\"")
+	var endIndex = strings.LastIndex(msg, "\"")
+	if startIndex == -1 || endIndex <= startIndex {
+		return
+	}
+	return strings.Replace(msg[startIndex+1:endIndex], "\"\"", "\"", -1), true
+}
+
+//切换远程目录
+func (this *Client) Cwd(dir string) (err error) {
+	_, err = this.cmdOK(FC_CWD, dir)
+	return
+}
+
+//切换到上一级远程目录
+func (this *Client) Cdup() (err error) {
+	_, err = this.cmdOK(FC_CDUP)
+	return
+}
+
+//删除远程文件
+func (this *Client) Delete(path string) (err error) {
+	_, err = this.cmdOK(FC_DELE, path)
+	return
+}
+
+//创建远程目录，返回服务器回复中的目录名，回复中没有目录名的时候返回path
+func (this *Client) Mkdir(path string) (dir string, err error) {
+	reply, err := this.cmdOK(FC_MKD, path)
+	if err != nil {
+		return
+	}
+	dir, ok := parsePathReply(reply)
+	if !ok {
+		dir = path
+	}
+	return
+}
+
+//删除远程目录，大部分服务器只能删除空目录
+func (this *Client) Rmdir(path string) (err error) {
+	_, err = this.cmdOK(FC_RMD, path)
+	return
+}
+
+//重命名远程文件或者目录，先发送RNFR，服务器回复350之后再发送RNTO
+func (this *Client) Rename(from string, to string) (err error) {
+	reply, err := this.cmd(FC_RNFR, from)
+	if err != nil {
+		return
+	}
+	if reply.Code != FC_RESP_CODE_FILE_ACTION_PENDING {
+		return newProtocolError([]string{FC_RNFR, from}, reply)
+	}
+	_, err = this.cmdOK(FC_RNTO, to)
+	return
+}
+
+//修改远程文件的权限，使用大部分Unix服务器支持的SITE CHMOD命令，
+//mode中的setuid、setgid和sticky位也会发送
+func (this *Client) Chmod(path string, mode os.FileMode) (err error) {
+	_, err = this.cmdOK(FC_SITE, "CHMOD", strconv.FormatUint(uint64(unixModeBits(mode)), 8), path)
+	return
+}
+
+//获取指定目录(dir为空时为当前目录)下的文件列表，服务器支持MLST的时候
+//使用MLSD，否则解析LIST命令的输出。LIST的输出中有不能解析的行的时候，
+//能解析的文件仍然会返回，同时返回*ListParseError
+func (this *Client) List(dir string) (entries []Entry, err error) {
+	if this.HasFeature(FEAT_MLST) {
+		entries, err = this.Mlsd(dir)
+		if !errors.Is(err, ErrSyntax) && !errors.Is(err, ErrNotImplemented) {
+			return
+		}
+	}
+	lines, err := this.ListLines(dir)
+	if err != nil {
+		return
+	}
+	return parseListLines(lines)
+}
+
+//获取指定目录(dir为空时为当前目录)下LIST命令返回的原始行
+func (this *Client) ListLines(dir string) (lines []string, err error) {
+	return this.dataLines(FC_LIST, dir)
+}
+
+//获取指定目录(dir为空时为当前目录)下的文件名列表
+func (this *Client) NameList(dir string) (names []string, err error) {
+	return this.dataLines(FC_NLST, dir)
+}
+
+//执行一个通过数据连接返回文本行的命令，比如LIST和NLST
+func (this *Client) dataLines(ftpCmd string, arg string) (lines []string, err error) {
+	var ftpParams = []string{ftpCmd}
+	if arg != "" {
+		ftpParams = append(ftpParams, arg)
+	}
+	dataConn, _, err := this.transfer(ftpParams...)
+	if err != nil {
+		return
+	}
+	var scanner = bufio.NewScanner(dataConn)
+	for scanner.Scan() {
+		var line = strings.TrimRight(scanner.Text(), "\r")
+		if line != "" {
+			lines = append(lines, this.decode(line))
+		}
+	}
+	_, err = this.finishTransfer(dataConn, ftpParams, scanner.Err())
+	return
+}
+
+//设置是否使用被动模式建立数据连接
+func (this *Client) SetPassive(passive bool) {
+	this.passive = passive
+}
+
+//是否使用被动模式建立数据连接
+func (this *Client) Passive() bool {
+	return this.passive
+}
+
+//建立数据连接并发送传输命令，服务器回复1xx之后返回数据连接和这个回复。
+//被动模式下先连接服务器再发送命令，主动模式下先监听端口再发送命令，
+//然后等待服务器连接过来
+func (this *Client) transfer(ftpParams ...string) (dataConn net.Conn, reply Reply, err error) {
+	return this.transferAt(0, ftpParams...)
+}
+
+//和transfer一样，offset大于0的时候在传输命令之前发送REST命令，让服务器
+//从offset处开始传输
+func (this *Client) transferAt(offset int64, ftpParams ...string) (dataConn net.Conn, reply Reply, err error) {
+	if err = this.syncProt(); err != nil {
+		return
+	}
+	var listener net.Listener
+	if this.passive {
+		dataConn, err = this.dialPassive()
+	} else {
+		listener, err = this.listenActive()
+	}
+	if err != nil {
+		return
+	}
+	//REST必须紧挨着传输命令发送
+	if offset > 0 {
+		reply, err = this.cmd(FC_REST, strconv.FormatInt(offset, 10))
+		if err == nil && reply.Code != FC_RESP_CODE_FILE_ACTION_PENDING {
+			err = newProtocolError([]string{FC_REST, strconv.FormatInt(offset, 10)}, reply)
+		}
+	}
+	if err == nil {
+		reply, err = this.cmd(ftpParams...)
+	}
+	if err == nil && !reply.IsPositivePreliminary() {
+		//服务器没有开始传输就结束了这个命令
+		err = newProtocolError(ftpParams, reply)
+	}
+	if err != nil {
+		if listener != nil {
+			listener.Close()
+		} else {
+			dataConn.Close()
+		}
+		dataConn = nil
+		return
+	}
+
+	//服务器已经开始传输了，后面出错的时候还要读取传输结束的回复，
+	//否则下一个命令会读到这个回复
+	if listener != nil {
+		dataConn, err = this.acceptActive(listener)
+		listener.Close()
+	}
+	if err == nil {
+		var plainConn = dataConn
+		if dataConn, err = this.wrapDataConn(plainConn); err != nil {
+			dataConn = plainConn
+		}
+	}
+	if err != nil {
+		if dataConn != nil {
+			dataConn.Close()
+			dataConn = nil
+		}
+		this.readReply()
+	}
+	return
+}
+
+//设置被动模式下是否总是优先使用EPSV
+func (this *Client) SetPreferEPSV(preferEPSV bool) {
+	this.opts.PreferEPSV = preferEPSV
+	this.useEPSV = preferEPSV || this.isIPv6() || this.features.Has(FEAT_EPSV)
+}
+
+//控制连接是否使用的IPv6，连接已经关闭的时候返回false
+func (this *Client) isIPv6() bool {
+	ip, err := this.remoteIP()
+	return err == nil && ip.To4() == nil
+}
+
+//被动模式下连接服务器指定的数据连接地址。FEAT列出了EPSV的时候先使用EPSV，
+//PASV失败并且服务器可能支持EPSV的时候改用EPSV，并且以后都使用EPSV；IPv4
+//连接上EPSV失败的时候也会改用PASV
+func (this *Client) dialPassive() (dataConn net.Conn, err error) {
+	//获取失败的时候当作服务器不支持FEAT，PASV和EPSV都可以尝试
+	features, _ := this.loadFeatures()
+	var pasvHost string
+	var pasvPort int
+	if this.useEPSV {
+		pasvHost, pasvPort, err = this.epsv()
+		if isProtocolError(err) && !this.isIPv6() {
+			this.useEPSV = false
+			pasvHost, pasvPort, err = this.pasv()
+		}
+	} else {
+		pasvHost, pasvPort, err = this.pasv()
+		//FEAT列出了扩展功能但是没有EPSV的时候，服务器不支持EPSV
+		if isProtocolError(err) && (len(features) == 0 || features.Has(FEAT_EPSV)) {
+			this.useEPSV = true
+			pasvHost, pasvPort, err = this.epsv()
+		}
+	}
+	if err != nil {
+		return
+	}
+	return net.DialTimeout("tcp", net.JoinHostPort(pasvHost, strconv.Itoa(pasvPort)), this.timeout)
+}
+
+//关闭数据连接并读取ftpParams所表示的传输命令结束的回复，transferErr为
+//传输过程中出现的错误
+func (this *Client) finishTransfer(dataConn net.Conn, ftpParams []string, transferErr error) (reply Reply, err error) {
+	dataConn.Close()
+	reply, err = this.readReply()
+	if err == nil && !reply.IsPositiveCompletion() {
+		err = newProtocolError(ftpParams, reply)
+	}
+	if transferErr != nil {
+		err = transferErr
+	}
+	return
+}
+
+//进入被动模式，返回数据连接地址，使用回复中的地址还是控制连接的
+//服务器地址由PasvAddrPolicy决定
+func (this *Client) pasv() (pasvHost string, pasvPort int, err error) {
+	reply, err := this.cmdOK(FC_PASV)
+	if err != nil {
+		return
+	}
+	//回复的格式一般为 227 Entering Passive Mode (h1,h2,h3,h4,p1,p2)，
+	//但是RFC 959并没有要求使用括号，所以直接查找6个用逗号分隔的数字
+	var pasvDataParts = pasvReplyRegexp.FindStringSubmatch(reply.Message())
+	if pasvDataParts == nil {
+		err = newProtocolError([]string{FC_PASV}, reply)
+		return
+	}
+	var numbers = make([]byte, 6)
+	for i, part := range pasvDataParts[1:] {
+		number, convErr := strconv.Atoi(part)
+		if convErr != nil || number > 255 {
+			err = newProtocolError([]string{FC_PASV}, reply)
+			return
+		}
+		numbers[i] = byte(number)
+	}
+	var replyIP = net.IPv4(numbers[0], numbers[1], numbers[2], numbers[3])
+	host, err := this.pasvHost(replyIP)
+	if err != nil {
+		return
+	}
+	pasvHost = host.String()
+	pasvPort = int(numbers[4])*256 + int(numbers[5])
+	return
+}
+
+//根据PasvAddrPolicy决定被动模式下连接的地址
+func (this *Client) pasvHost(replyIP net.IP) (host net.IP, err error) {
+	controlIP, err := this.remoteIP()
+	if err != nil {
+		return
+	}
+	switch this.opts.PasvAddrPolicy {
+	case PASV_ADDR_POLICY_TRUST_REPLY:
+		return replyIP, nil
+	case PASV_ADDR_POLICY_USE_CONTROL_HOST:
+		return controlIP, nil
+	}
+	//NAT后面的服务器经常回复内网地址，而回复其他主机的地址可能是
+	//服务器被利用来把数据连接重定向到第三方主机，这两种情况都改用
+	//控制连接的服务器地址
+	if replyIP.IsPrivate() || replyIP.IsLoopback() || replyIP.IsUnspecified() ||
+		replyIP.IsLinkLocalUnicast() || !replyIP.Equal(controlIP) {
+		return controlIP, nil
+	}
+	return replyIP, nil
+}
+
+//进入扩展被动模式，EPSV的回复中只有端口号，数据连接使用控制连接的
+//服务器地址，定义见RFC 2428
+func (this *Client) epsv() (pasvHost string, pasvPort int, err error) {
+	reply, err := this.cmdOK(FC_EPSV)
+	if err != nil {
+		return
+	}
+	var msg = reply.Message()
+	//回复的格式为 229 Entering Extended Passive Mode (|||port|)，
+	//其中的分隔符`|`也可以是其他的字符
+	var startIndex = strings.Index(msg, "(")
+	var endIndex = strings.LastIndex(msg, ")")
+	if startIndex == -1 || endIndex <= startIndex+1 {
+		err = newProtocolError([]string{FC_EPSV}, reply)
+		return
+	}
+	var epsvData = msg[startIndex+1 : endIndex]
+	var epsvDataParts = strings.Split(epsvData, epsvData[:1])
+	if len(epsvDataParts) != 5 {
+		err = newProtocolError([]string{FC_EPSV}, reply)
+		return
+	}
+	pasvPort, err = strconv.Atoi(epsvDataParts[3])
+	if err != nil || pasvPort <= 0 || pasvPort > 65535 {
+		err = newProtocolError([]string{FC_EPSV}, reply)
+		return
+	}
+	controlIP, err := this.remoteIP()
+	if err != nil {
+		return
+	}
+	pasvHost = controlIP.String()
+	return
+}
+
+//发送QUIT命令并关闭控制连接
+func (this *Client) Quit() (err error) {
+	if this.conn == nil {
+		return
+	}
+	_, err = this.cmd(FC_QUIT)
+	this.Close()
+	return
+}
+
+//直接关闭控制连接
+func (this *Client) Close() (err error) {
+	if this.conn == nil {
+		return
+	}
+	err = this.conn.Close()
+	this.conn = nil
+	return
+}
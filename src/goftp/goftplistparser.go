@@ -0,0 +1,383 @@
+package goftp
+
+import (
+	"errors"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//LIST命令的输出没有统一的格式，不同的服务器返回的格式各不相同，这里
+//依次尝试每一种解析器，第一个认识这一行的解析器的结果就是最终的结果
+var (
+	ErrUnknownListFormat = errors.New("unknown LIST line format") //解析器不认识这一行的格式，继续尝试下一个解析器
+	ErrSkipListLine      = errors.New("not a LIST entry")         //这一行是标题或者汇总之类的内容，不是文件
+)
+
+//解析LIST命令返回的一行，不认识这一行的时候返回ErrUnknownListFormat，
+//这一行不是文件的时候返回ErrSkipListLine
+type ListParser func(line string) (entry Entry, err error)
+
+//LIST命令返回的行中有不能解析的行时返回的错误，能解析的行仍然会返回
+type ListParseError struct {
+	Lines []string //不能解析的行
+}
+
+func (this *ListParseError) Error() string {
+	return strconv.Itoa(len(this.Lines)) + " unparsable LIST line(s), first: " + this.Lines[0]
+}
+
+var (
+	listParsersMutex sync.RWMutex
+	listParsers      = []ListParser{
+		parseUnixListLine,
+		parseWindowsListLine,
+		parseEPLFListLine,
+		parseVMSListLine,
+		parseMVSListLine,
+	}
+)
+
+//注册一个LIST行解析器，后注册的解析器比内置的解析器先尝试
+func RegisterListParser(parser ListParser) {
+	listParsersMutex.Lock()
+	defer listParsersMutex.Unlock()
+	listParsers = append([]ListParser{parser}, listParsers...)
+}
+
+//依次使用注册的解析器解析LIST命令返回的一行
+func ParseListLine(line string) (entry Entry, err error) {
+	listParsersMutex.RLock()
+	defer listParsersMutex.RUnlock()
+	for _, parser := range listParsers {
+		entry, err = parser(line)
+		if err != ErrUnknownListFormat {
+			if err == nil {
+				entry.Raw = line
+			}
+			return
+		}
+	}
+	return
+}
+
+//解析LIST命令返回的所有行，`.`和`..`不会返回，不能解析的行通过
+//*ListParseError报告
+func parseListLines(lines []string) (entries []Entry, err error) {
+	var badLines []string
+	entries = make([]Entry, 0, len(lines))
+	for _, line := range lines {
+		entry, parseErr := ParseListLine(line)
+		if parseErr == ErrSkipListLine || (parseErr == nil && (entry.Name == "." || entry.Name == "..")) {
+			continue
+		}
+		if parseErr != nil {
+			badLines = append(badLines, line)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if len(badLines) > 0 {
+		err = &ListParseError{Lines: badLines}
+	}
+	return
+}
+
+//Unix的`ls -l`风格，有的服务器没有group字段，设备文件的大小是`major, minor`，
+//半年之内的文件显示时间，更早的文件显示年份
+//
+//	-rw-r--r--   1 owner group     1234 Mar 14 12:00 name
+//	lrwxrwxrwx   1 owner group        4 Mar 14  2020 link -> target
+var unixListRegexp = regexp.MustCompile(`^([-bcdlps])([-rwxsStT]{9})[+@.]?\s+(?:\d+\s+)?(\S+)\s+(?:(\S+)\s+)?(\d+|\d+,\s*\d+)\s+([A-Za-z]{3})\s+(\d{1,2})\s+(\d{1,2}:\d{2}|\d{4})\s+(.*)$`)
+
+func parseUnixListLine(line string) (entry Entry, err error) {
+	if strings.HasPrefix(line, "total ") {
+		err = ErrSkipListLine
+		return
+	}
+	var matches = unixListRegexp.FindStringSubmatch(line)
+	if matches == nil {
+		err = ErrUnknownListFormat
+		return
+	}
+	month, ok := parseMonth(matches[6])
+	if !ok {
+		err = ErrUnknownListFormat
+		return
+	}
+	switch matches[1] {
+	case "d":
+		entry.Type = ENTRY_TYPE_DIR
+	case "l":
+		entry.Type = ENTRY_TYPE_LINK
+	default:
+		entry.Type = ENTRY_TYPE_FILE
+	}
+	entry.UnixMode = unixPermMode(matches[2], entry.Type)
+	entry.Owner = matches[3]
+	entry.Size, _ = strconv.ParseInt(matches[5], 10, 64)
+
+	var day, _ = strconv.Atoi(matches[7])
+	if strings.Contains(matches[8], ":") {
+		var hourMin = strings.SplitN(matches[8], ":", 2)
+		var hour, _ = strconv.Atoi(hourMin[0])
+		var minute, _ = strconv.Atoi(hourMin[1])
+		entry.ModTime = guessYear(month, day, hour, minute)
+	} else {
+		var year, _ = strconv.Atoi(matches[8])
+		entry.ModTime = time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+
+	entry.Name = matches[9]
+	if entry.Type == ENTRY_TYPE_LINK {
+		if index := strings.Index(entry.Name, " -> "); index != -1 {
+			entry.Target = entry.Name[index+len(" -> "):]
+			entry.Name = entry.Name[:index]
+		}
+	}
+	return
+}
+
+//把`rwxr-xr-x`这样的权限转换为os.FileMode
+func unixPermMode(perm string, entryType EntryType) os.FileMode {
+	var mode uint32
+	for i, c := range perm {
+		if c != '-' && c != 'S' && c != 'T' {
+			mode |= 1 << uint(8-i)
+		}
+	}
+	if perm[2] == 's' || perm[2] == 'S' {
+		mode |= 04000
+	}
+	if perm[5] == 's' || perm[5] == 'S' {
+		mode |= 02000
+	}
+	if perm[8] == 't' || perm[8] == 'T' {
+		mode |= 01000
+	}
+	return unixFileMode(mode, entryType)
+}
+
+//`ls -l`对半年之内的文件只显示月份、日期和时间，年份是今年，如果这样
+//得到的时间在将来，那就是去年的文件。服务器的时区无从知道，当作UTC
+func guessYear(month time.Month, day int, hour int, minute int) time.Time {
+	var now = time.Now().UTC()
+	var t = time.Date(now.Year(), month, day, hour, minute, 0, 0, time.UTC)
+	//允许服务器的时钟比本地快一点
+	if t.After(now.Add(24 * time.Hour)) {
+		t = time.Date(now.Year()-1, month, day, hour, minute, 0, 0, time.UTC)
+	}
+	return t
+}
+
+//解析英文的月份缩写，不区分大小写
+func parseMonth(name string) (month time.Month, ok bool) {
+	for m := time.January; m <= time.December; m++ {
+		if strings.EqualFold(m.String()[:3], name) {
+			return m, true
+		}
+	}
+	return
+}
+
+//Windows和IIS的风格，年份可能是两位也可能是四位，时间可能是12小时制
+//也可能是24小时制
+//
+//	03-14-24  12:00PM       <DIR>          name
+//	03-14-2024  13:00             1234 name
+var windowsListRegexp = regexp.MustCompile(`^(\d{2})-(\d{2})-(\d{2}|\d{4})\s+(\d{1,2}):(\d{2})\s*([AaPp][Mm])?\s+(<DIR>|\d+)\s+(.+)$`)
+
+func parseWindowsListLine(line string) (entry Entry, err error) {
+	var matches = windowsListRegexp.FindStringSubmatch(line)
+	if matches == nil {
+		err = ErrUnknownListFormat
+		return
+	}
+	var month, _ = strconv.Atoi(matches[1])
+	var day, _ = strconv.Atoi(matches[2])
+	var year, _ = strconv.Atoi(matches[3])
+	if len(matches[3]) == 2 {
+		//和strptime的%y一样，69以后是19xx年
+		if year < 69 {
+			year += 2000
+		} else {
+			year += 1900
+		}
+	}
+	var hour, _ = strconv.Atoi(matches[4])
+	var minute, _ = strconv.Atoi(matches[5])
+	switch strings.ToUpper(matches[6]) {
+	case "AM":
+		if hour == 12 {
+			hour = 0
+		}
+	case "PM":
+		if hour != 12 {
+			hour += 12
+		}
+	}
+	entry.ModTime = time.Date(year, time.Month(month), day, hour, minute, 0, 0, time.UTC)
+	if matches[7] == "<DIR>" {
+		entry.Type = ENTRY_TYPE_DIR
+	} else {
+		entry.Type = ENTRY_TYPE_FILE
+		entry.Size, _ = strconv.ParseInt(matches[7], 10, 64)
+	}
+	entry.Name = matches[8]
+	return
+}
+
+//EPLF(Easily Parsed LIST Format)，以`+`开头，用逗号分隔的信息后面跟着
+//一个TAB和文件名，`/`表示目录，`r`表示可以下载的文件，`s`是大小，`m`是
+//Unix时间戳，`i`是唯一标识，`up`是八进制的权限
+//
+//	+i8388621.29609,m824255902,/,	dev
+//	+i8388621.44468,m839956783,r,s10376,up644,	RFCEPLF
+func parseEPLFListLine(line string) (entry Entry, err error) {
+	var tab = strings.Index(line, "\t")
+	if !strings.HasPrefix(line, "+") || tab == -1 {
+		err = ErrUnknownListFormat
+		return
+	}
+	entry.Name = line[tab+1:]
+	entry.Type = ENTRY_TYPE_FILE
+	var mode = -1
+	for _, fact := range strings.Split(line[1:tab], ",") {
+		if fact == "" {
+			continue
+		}
+		switch fact[0] {
+		case '/':
+			entry.Type = ENTRY_TYPE_DIR
+		case 's':
+			entry.Size, _ = strconv.ParseInt(fact[1:], 10, 64)
+		case 'm':
+			if seconds, parseErr := strconv.ParseInt(fact[1:], 10, 64); parseErr == nil {
+				entry.ModTime = time.Unix(seconds, 0).UTC()
+			}
+		case 'i':
+			entry.Unique = fact[1:]
+		case 'u':
+			if strings.HasPrefix(fact, "up") {
+				if perm, parseErr := strconv.ParseUint(fact[2:], 8, 32); parseErr == nil {
+					mode = int(perm)
+				}
+			}
+		}
+	}
+	if mode != -1 {
+		entry.UnixMode = unixFileMode(uint32(mode), entry.Type)
+	}
+	return
+}
+
+//OpenVMS的风格，文件名后面是版本号，目录的文件名以`.DIR`结尾，大小是
+//512字节的块数，后面是修改时间、所有者和权限
+//
+//	Directory DISK$USER:[ANONYMOUS]
+//
+//	FILE.TXT;1          2/3      14-MAR-2024 12:00:00  [GROUP,OWNER]  (RWED,RWED,RE,)
+//	SUBDIR.DIR;1        1/3      14-MAR-2024 12:00     [OWNER]        (RWE,RWE,RE,RE)
+//
+//	Total of 2 files, 3/6 blocks.
+var vmsListRegexp = regexp.MustCompile(`^(\S+);\d+\s+(\d+)(?:/\d+)?\s+(\d{1,2})-([A-Za-z]{3})-(\d{4})\s+(\d{1,2}):(\d{2})(?::(\d{2}))?(?:\.\d+)?(?:\s+\[([^\]]*)\])?(?:\s+\(([^)]*)\))?\s*$`)
+
+//VMS的一个块是512字节
+const VMS_BLOCK_SIZE = 512
+
+func parseVMSListLine(line string) (entry Entry, err error) {
+	if strings.HasPrefix(line, "Directory ") || strings.HasPrefix(line, "Total of ") || strings.HasPrefix(line, "Grand total of ") {
+		err = ErrSkipListLine
+		return
+	}
+	var matches = vmsListRegexp.FindStringSubmatch(line)
+	if matches == nil {
+		err = ErrUnknownListFormat
+		return
+	}
+	month, ok := parseMonth(matches[4])
+	if !ok {
+		err = ErrUnknownListFormat
+		return
+	}
+	entry.Name = matches[1]
+	entry.Type = ENTRY_TYPE_FILE
+	if strings.HasSuffix(strings.ToUpper(entry.Name), ".DIR") {
+		entry.Type = ENTRY_TYPE_DIR
+		entry.Name = entry.Name[:len(entry.Name)-len(".DIR")]
+	}
+	var blocks, _ = strconv.ParseInt(matches[2], 10, 64)
+	entry.Size = blocks * VMS_BLOCK_SIZE
+	var day, _ = strconv.Atoi(matches[3])
+	var year, _ = strconv.Atoi(matches[5])
+	var hour, _ = strconv.Atoi(matches[6])
+	var minute, _ = strconv.Atoi(matches[7])
+	var second, _ = strconv.Atoi(matches[8])
+	entry.ModTime = time.Date(year, month, day, hour, minute, second, 0, time.UTC)
+	//[GROUP,OWNER]或者[OWNER]
+	if owner := matches[9]; owner != "" {
+		if index := strings.LastIndex(owner, ","); index != -1 {
+			owner = owner[index+1:]
+		}
+		entry.Owner = owner
+	}
+	return
+}
+
+//MVS(z/OS)的数据集列表，分区数据集(PO)相当于目录，里面的成员相当于
+//文件，迁移到磁带的数据集只有名字。也支持列出分区数据集成员的格式
+//
+//	Volume Unit    Referred Ext Used Recfm Lrecl BlkSz Dsorg Dsname
+//	WYNK01 3380K   2024/03/14  1   15  FB     80  3120  PO  ASM.SOURCE
+//	WYNK02 3390    2024/03/13  2  150  VB    255 27998  PS  DATA.SET
+//	Migrated                                                OLD.DATA
+//
+//	 Name     VV.MM   Created       Changed      Size  Init   Mod   Id
+//	MEMBER1   01.03 2024/03/14 2024/03/14 12:00    10    10     0 USER
+var (
+	mvsDatasetRegexp = regexp.MustCompile(`^\S+\s+\S+\s+(\d{4})/(\d{2})/(\d{2})\s+\d+\s+\d+\s+\S+\s+\d+\s+\d+\s+(\S+)\s+(\S+)$`)
+	mvsMemberRegexp  = regexp.MustCompile(`^(\S+)\s+\d{2}\.\d{2}\s+\d{4}/\d{2}/\d{2}\s+(\d{4})/(\d{2})/(\d{2})\s+(\d{2}):(\d{2})(?::\d{2})?\s+\d+\s+\d+\s+\d+\s+(\S+)$`)
+)
+
+func parseMVSListLine(line string) (entry Entry, err error) {
+	if strings.HasPrefix(line, "Volume ") || strings.HasPrefix(strings.TrimSpace(line), "Name ") {
+		err = ErrSkipListLine
+		return
+	}
+	var fields = strings.Fields(line)
+	if len(fields) == 2 && (fields[0] == "Migrated" || fields[0] == "ARCIVE") {
+		entry.Name = fields[1]
+		entry.Type = ENTRY_TYPE_FILE
+		return
+	}
+	if matches := mvsDatasetRegexp.FindStringSubmatch(line); matches != nil {
+		var year, _ = strconv.Atoi(matches[1])
+		var month, _ = strconv.Atoi(matches[2])
+		var day, _ = strconv.Atoi(matches[3])
+		entry.ModTime = time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+		entry.Type = ENTRY_TYPE_FILE
+		if matches[4] == "PO" || matches[4] == "PO-E" {
+			entry.Type = ENTRY_TYPE_DIR
+		}
+		entry.Name = matches[5]
+		return
+	}
+	if matches := mvsMemberRegexp.FindStringSubmatch(line); matches != nil {
+		entry.Name = matches[1]
+		entry.Type = ENTRY_TYPE_FILE
+		var year, _ = strconv.Atoi(matches[2])
+		var month, _ = strconv.Atoi(matches[3])
+		var day, _ = strconv.Atoi(matches[4])
+		var hour, _ = strconv.Atoi(matches[5])
+		var minute, _ = strconv.Atoi(matches[6])
+		entry.ModTime = time.Date(year, time.Month(month), day, hour, minute, 0, 0, time.UTC)
+		//成员的Size是记录数，不是字节数，所以不使用
+		entry.Owner = matches[7]
+		return
+	}
+	err = ErrUnknownListFormat
+	return
+}
@@ -0,0 +1,252 @@
+package goftp
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+//只比较解析器填写的字段，Raw由ParseListLine填写
+func checkListEntry(t *testing.T, name string, got Entry, want Entry) {
+	if got.Name != want.Name || got.Type != want.Type || got.Size != want.Size || !got.ModTime.Equal(want.ModTime) ||
+		got.UnixMode != want.UnixMode || got.Owner != want.Owner || got.Target != want.Target || got.Unique != want.Unique {
+		t.Errorf("%s: got %+v, want %+v", name, got, want)
+	}
+}
+
+type listLineTest struct {
+	name  string
+	line  string
+	entry Entry
+	err   error
+}
+
+func runListLineTests(t *testing.T, parser ListParser, tests []listLineTest) {
+	for _, test := range tests {
+		entry, err := parser(test.line)
+		if err != test.err {
+			t.Errorf("%s: error = %v, want %v", test.name, err, test.err)
+			continue
+		}
+		if err == nil {
+			checkListEntry(t, test.name, entry, test.entry)
+		}
+	}
+}
+
+func date(year int, month time.Month, day int, hour int, minute int, second int) time.Time {
+	return time.Date(year, month, day, hour, minute, second, 0, time.UTC)
+}
+
+func TestParseUnixListLine(t *testing.T) {
+	runListLineTests(t, parseUnixListLine, []listLineTest{
+		{"file with year", "-rw-r--r--   1 owner group     1234 Mar 14  2020 readme.txt",
+			Entry{Name: "readme.txt", Type: ENTRY_TYPE_FILE, Size: 1234, ModTime: date(2020, 3, 14, 0, 0, 0), UnixMode: 0644, Owner: "owner"}, nil},
+		{"dir", "drwxr-xr-x   2 owner group     4096 Jan  1  1999 src",
+			Entry{Name: "src", Type: ENTRY_TYPE_DIR, Size: 4096, ModTime: date(1999, 1, 1, 0, 0, 0), UnixMode: os.ModeDir | 0755, Owner: "owner"}, nil},
+		{"symlink", "lrwxrwxrwx   1 owner group        4 Mar 14  2020 link -> target",
+			Entry{Name: "link", Type: ENTRY_TYPE_LINK, Size: 4, ModTime: date(2020, 3, 14, 0, 0, 0), UnixMode: os.ModeSymlink | 0777, Owner: "owner", Target: "target"}, nil},
+		//链接名和目标中都可以有空格，第一个` -> `是分隔符
+		{"symlink with spaces", "lrwxrwxrwx   1 owner group       12 Mar 14  2020 my link -> ../my target",
+			Entry{Name: "my link", Type: ENTRY_TYPE_LINK, Size: 12, ModTime: date(2020, 3, 14, 0, 0, 0), UnixMode: os.ModeSymlink | 0777, Owner: "owner", Target: "../my target"}, nil},
+		{"arrow in file name", "-rw-r--r--   1 owner group        0 Mar 14  2020 a -> b",
+			Entry{Name: "a -> b", Type: ENTRY_TYPE_FILE, ModTime: date(2020, 3, 14, 0, 0, 0), UnixMode: 0644, Owner: "owner"}, nil},
+		{"name with spaces", "-rw-r--r--   1 owner group        7 Dec 31  2023 two  spaces ",
+			Entry{Name: "two  spaces ", Type: ENTRY_TYPE_FILE, Size: 7, ModTime: date(2023, 12, 31, 0, 0, 0), UnixMode: 0644, Owner: "owner"}, nil},
+		{"no group", "-rw-r--r--   1 owner        5 Mar 14  2020 a",
+			Entry{Name: "a", Type: ENTRY_TYPE_FILE, Size: 5, ModTime: date(2020, 3, 14, 0, 0, 0), UnixMode: 0644, Owner: "owner"}, nil},
+		{"no link count", "-rw-r--r-- owner group 5 Mar 14 2020 a",
+			Entry{Name: "a", Type: ENTRY_TYPE_FILE, Size: 5, ModTime: date(2020, 3, 14, 0, 0, 0), UnixMode: 0644, Owner: "owner"}, nil},
+		{"acl marker", "-rw-r--r--+  1 owner group        5 Mar 14  2020 a",
+			Entry{Name: "a", Type: ENTRY_TYPE_FILE, Size: 5, ModTime: date(2020, 3, 14, 0, 0, 0), UnixMode: 0644, Owner: "owner"}, nil},
+		{"device", "crw-rw-rw-   1 root  root    1,   3 Mar 14  2020 null",
+			Entry{Name: "null", Type: ENTRY_TYPE_FILE, ModTime: date(2020, 3, 14, 0, 0, 0), UnixMode: 0666, Owner: "root"}, nil},
+		{"setuid", "-rwsr-xr-x   1 root  root     100 Mar 14  2020 passwd",
+			Entry{Name: "passwd", Type: ENTRY_TYPE_FILE, Size: 100, ModTime: date(2020, 3, 14, 0, 0, 0), UnixMode: os.ModeSetuid | 0755, Owner: "root"}, nil},
+		{"setgid without exec", "-rw-r-Sr--   1 root  root     100 Mar 14  2020 a",
+			Entry{Name: "a", Type: ENTRY_TYPE_FILE, Size: 100, ModTime: date(2020, 3, 14, 0, 0, 0), UnixMode: os.ModeSetgid | 0644, Owner: "root"}, nil},
+		{"sticky", "drwxrwxrwt   9 root  root    4096 Mar 14  2020 tmp",
+			Entry{Name: "tmp", Type: ENTRY_TYPE_DIR, Size: 4096, ModTime: date(2020, 3, 14, 0, 0, 0), UnixMode: os.ModeDir | os.ModeSticky | 0777, Owner: "root"}, nil},
+		{"total", "total 12", Entry{}, ErrSkipListLine},
+		{"bad month", "-rw-r--r--   1 owner group        5 Foo 14  2020 a", Entry{}, ErrUnknownListFormat},
+		{"windows line", "03-14-24  12:00PM       <DIR>          name", Entry{}, ErrUnknownListFormat},
+		{"garbage", "this is not a listing line", Entry{}, ErrUnknownListFormat},
+	})
+}
+
+func TestParseUnixListLineRecentDate(t *testing.T) {
+	//半年之内的文件只显示时间，年份是今年，得到的时间在将来的时候是去年
+	var now = time.Now().UTC().Truncate(time.Minute)
+	var past = now.AddDate(0, 0, -10)
+	var future = now.AddDate(0, 0, 10)
+	var tests = []struct {
+		name string
+		t    time.Time
+		want time.Time
+	}{
+		{"past", past, past},
+		{"a few hours later", now.Add(3 * time.Hour), now.Add(3 * time.Hour)},
+		{"future", future, date(future.Year()-1, future.Month(), future.Day(), future.Hour(), future.Minute(), 0)},
+	}
+	for _, test := range tests {
+		var line = "-rw-r--r--   1 owner group        5 " + test.t.Format("Jan _2 15:04") + " a"
+		entry, err := parseUnixListLine(line)
+		if err != nil {
+			t.Errorf("%s: parseUnixListLine(%q) error = %v", test.name, line, err)
+			continue
+		}
+		if !entry.ModTime.Equal(test.want) {
+			t.Errorf("%s: parseUnixListLine(%q) ModTime = %v, want %v", test.name, line, entry.ModTime, test.want)
+		}
+	}
+}
+
+func TestParseWindowsListLine(t *testing.T) {
+	runListLineTests(t, parseWindowsListLine, []listLineTest{
+		{"dir", "03-14-24  12:00PM       <DIR>          name",
+			Entry{Name: "name", Type: ENTRY_TYPE_DIR, ModTime: date(2024, 3, 14, 12, 0, 0)}, nil},
+		{"file", "03-14-2024  01:05PM             1234 my file.txt",
+			Entry{Name: "my file.txt", Type: ENTRY_TYPE_FILE, Size: 1234, ModTime: date(2024, 3, 14, 13, 5, 0)}, nil},
+		{"midnight", "03-14-24  12:30AM                0 a",
+			Entry{Name: "a", Type: ENTRY_TYPE_FILE, ModTime: date(2024, 3, 14, 0, 30, 0)}, nil},
+		{"24 hour", "03-14-2024  13:00             1234 a",
+			Entry{Name: "a", Type: ENTRY_TYPE_FILE, Size: 1234, ModTime: date(2024, 3, 14, 13, 0, 0)}, nil},
+		{"two digit year before 69", "01-02-68  09:00AM                1 a",
+			Entry{Name: "a", Type: ENTRY_TYPE_FILE, Size: 1, ModTime: date(2068, 1, 2, 9, 0, 0)}, nil},
+		{"two digit year from 69", "01-02-99  09:00am                1 a",
+			Entry{Name: "a", Type: ENTRY_TYPE_FILE, Size: 1, ModTime: date(1999, 1, 2, 9, 0, 0)}, nil},
+		{"unix line", "-rw-r--r--   1 owner group     1234 Mar 14 12:00 name", Entry{}, ErrUnknownListFormat},
+	})
+}
+
+func TestParseEPLFListLine(t *testing.T) {
+	runListLineTests(t, parseEPLFListLine, []listLineTest{
+		{"dir", "+i8388621.29609,m824255902,/,\tdev",
+			Entry{Name: "dev", Type: ENTRY_TYPE_DIR, ModTime: time.Unix(824255902, 0), Unique: "8388621.29609"}, nil},
+		{"file", "+i8388621.44468,m839956783,r,s10376,up644,\tRFCEPLF",
+			Entry{Name: "RFCEPLF", Type: ENTRY_TYPE_FILE, Size: 10376, ModTime: time.Unix(839956783, 0), Unique: "8388621.44468", UnixMode: 0644}, nil},
+		{"name with spaces", "+r,s5,\tmy file", Entry{Name: "my file", Type: ENTRY_TYPE_FILE, Size: 5}, nil},
+		{"no tab", "+i1,r,s5, name", Entry{}, ErrUnknownListFormat},
+		{"no plus", "i1,r,s5,\tname", Entry{}, ErrUnknownListFormat},
+	})
+}
+
+func TestParseVMSListLine(t *testing.T) {
+	runListLineTests(t, parseVMSListLine, []listLineTest{
+		{"file", "FILE.TXT;1          2/3      14-MAR-2024 12:00:30  [GROUP,OWNER]  (RWED,RWED,RE,)",
+			Entry{Name: "FILE.TXT", Type: ENTRY_TYPE_FILE, Size: 2 * VMS_BLOCK_SIZE, ModTime: date(2024, 3, 14, 12, 0, 30), Owner: "OWNER"}, nil},
+		{"dir", "SUBDIR.DIR;1        1/3      14-MAR-2024 12:00     [OWNER]        (RWE,RWE,RE,RE)",
+			Entry{Name: "SUBDIR", Type: ENTRY_TYPE_DIR, Size: VMS_BLOCK_SIZE, ModTime: date(2024, 3, 14, 12, 0, 0), Owner: "OWNER"}, nil},
+		{"no owner", "A.B;12  5  1-Jan-2000 00:00:00.00",
+			Entry{Name: "A.B", Type: ENTRY_TYPE_FILE, Size: 5 * VMS_BLOCK_SIZE, ModTime: date(2000, 1, 1, 0, 0, 0)}, nil},
+		{"directory header", "Directory DISK$USER:[ANONYMOUS]", Entry{}, ErrSkipListLine},
+		{"total", "Total of 2 files, 3/6 blocks.", Entry{}, ErrSkipListLine},
+		{"grand total", "Grand total of 1 directory, 2 files, 3/6 blocks.", Entry{}, ErrSkipListLine},
+		{"bad month", "A.B;1  5  1-FOO-2000 00:00:00", Entry{}, ErrUnknownListFormat},
+		{"no version", "FILE.TXT  2/3  14-MAR-2024 12:00:00", Entry{}, ErrUnknownListFormat},
+	})
+}
+
+func TestParseMVSListLine(t *testing.T) {
+	runListLineTests(t, parseMVSListLine, []listLineTest{
+		{"partitioned dataset", "WYNK01 3380K   2024/03/14  1   15  FB     80  3120  PO  ASM.SOURCE",
+			Entry{Name: "ASM.SOURCE", Type: ENTRY_TYPE_DIR, ModTime: date(2024, 3, 14, 0, 0, 0)}, nil},
+		{"sequential dataset", "WYNK02 3390    2024/03/13  2  150  VB    255 27998  PS  DATA.SET",
+			Entry{Name: "DATA.SET", Type: ENTRY_TYPE_FILE, ModTime: date(2024, 3, 13, 0, 0, 0)}, nil},
+		{"migrated", "Migrated                                                OLD.DATA",
+			Entry{Name: "OLD.DATA", Type: ENTRY_TYPE_FILE}, nil},
+		{"member", "MEMBER1   01.03 2024/03/14 2024/03/15 12:34    10    10     0 USER",
+			Entry{Name: "MEMBER1", Type: ENTRY_TYPE_FILE, ModTime: date(2024, 3, 15, 12, 34, 0), Owner: "USER"}, nil},
+		{"dataset header", "Volume Unit    Referred Ext Used Recfm Lrecl BlkSz Dsorg Dsname", Entry{}, ErrSkipListLine},
+		{"member header", " Name     VV.MM   Created       Changed      Size  Init   Mod   Id", Entry{}, ErrSkipListLine},
+		{"garbage", "this is not a listing line", Entry{}, ErrUnknownListFormat},
+	})
+}
+
+func TestParseListLine(t *testing.T) {
+	var tests = []struct {
+		line  string
+		name  string
+		entry Entry
+	}{
+		{"-rw-r--r--   1 owner group     1234 Mar 14  2020 unix", "unix",
+			Entry{Name: "unix", Type: ENTRY_TYPE_FILE, Size: 1234, ModTime: date(2020, 3, 14, 0, 0, 0), UnixMode: 0644, Owner: "owner"}},
+		{"03-14-24  12:00PM       <DIR>          windows", "windows",
+			Entry{Name: "windows", Type: ENTRY_TYPE_DIR, ModTime: date(2024, 3, 14, 12, 0, 0)}},
+		{"+r,s5,\teplf", "eplf", Entry{Name: "eplf", Type: ENTRY_TYPE_FILE, Size: 5}},
+		{"VMS.TXT;1  1  14-MAR-2024 12:00", "vms", Entry{Name: "VMS.TXT", Type: ENTRY_TYPE_FILE, Size: VMS_BLOCK_SIZE, ModTime: date(2024, 3, 14, 12, 0, 0)}},
+		{"Migrated  MVS.DATA", "mvs", Entry{Name: "MVS.DATA", Type: ENTRY_TYPE_FILE}},
+	}
+	for _, test := range tests {
+		entry, err := ParseListLine(test.line)
+		if err != nil {
+			t.Errorf("%s: ParseListLine(%q) error = %v", test.name, test.line, err)
+			continue
+		}
+		if entry.Raw != test.line {
+			t.Errorf("%s: Raw = %q, want %q", test.name, entry.Raw, test.line)
+		}
+		checkListEntry(t, test.name, entry, test.entry)
+	}
+	if _, err := ParseListLine("this is not a listing line"); err != ErrUnknownListFormat {
+		t.Errorf("ParseListLine() error = %v, want %v", err, ErrUnknownListFormat)
+	}
+}
+
+func TestParseListLines(t *testing.T) {
+	entries, err := parseListLines([]string{
+		"total 8",
+		"drwxr-xr-x   2 owner group     4096 Mar 14  2020 .",
+		"drwxr-xr-x   2 owner group     4096 Mar 14  2020 ..",
+		"-rw-r--r--   1 owner group        5 Mar 14  2020 a",
+		"this is not a listing line",
+		"lrwxrwxrwx   1 owner group        1 Mar 14  2020 b -> a",
+		"neither is this",
+	})
+	//不能解析的行通过ListParseError报告，能解析的行仍然返回
+	parseErr, ok := err.(*ListParseError)
+	if !ok {
+		t.Fatalf("parseListLines() error = %v, want *ListParseError", err)
+	}
+	if want := []string{"this is not a listing line", "neither is this"}; !reflect.DeepEqual(parseErr.Lines, want) {
+		t.Errorf("ListParseError.Lines = %q, want %q", parseErr.Lines, want)
+	}
+	if len(entries) != 2 || entries[0].Name != "a" || entries[1].Name != "b" || entries[1].Target != "a" {
+		t.Errorf("parseListLines() = %+v, want a and b -> a", entries)
+	}
+
+	entries, err = parseListLines([]string{"total 0"})
+	if err != nil || len(entries) != 0 {
+		t.Errorf("parseListLines() = %+v, %v, want no entries", entries, err)
+	}
+}
+
+func TestRegisterListParser(t *testing.T) {
+	listParsersMutex.RLock()
+	var saved = listParsers
+	listParsersMutex.RUnlock()
+	defer func() {
+		listParsersMutex.Lock()
+		listParsers = saved
+		listParsersMutex.Unlock()
+	}()
+
+	//后注册的解析器比内置的解析器先尝试
+	RegisterListParser(func(line string) (entry Entry, err error) {
+		if line == "custom" || line == "total 1" {
+			entry.Name = "custom"
+			return
+		}
+		err = ErrUnknownListFormat
+		return
+	})
+	for _, line := range []string{"custom", "total 1"} {
+		if entry, err := ParseListLine(line); err != nil || entry.Name != "custom" {
+			t.Errorf("ParseListLine(%q) = %+v, %v, want custom", line, entry, err)
+		}
+	}
+	if entry, err := ParseListLine("+r,s5,\teplf"); err != nil || entry.Name != "eplf" {
+		t.Errorf("ParseListLine() = %+v, %v, want eplf", entry, err)
+	}
+}
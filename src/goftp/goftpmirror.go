@@ -0,0 +1,511 @@
+package goftp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//同步目录时执行的操作
+type MirrorActionType string
+
+const (
+	MIRROR_ACTION_MKDIR    MirrorActionType = "mkdir"    //在目标端创建目录
+	MIRROR_ACTION_TRANSFER MirrorActionType = "transfer" //传输新的或者改变了的文件
+	MIRROR_ACTION_DELETE   MirrorActionType = "delete"   //删除目标端多余的文件
+	MIRROR_ACTION_RMDIR    MirrorActionType = "rmdir"    //删除目标端多余的目录
+)
+
+//比较修改时间时允许的误差。MLSD和MDTM的时间精确到秒，服务器只能提供
+//LIST中的时间的时候使用MIRROR_LIST_TIME_TOLERANCE，LIST中的时间只精确到分钟
+const (
+	MIRROR_TIME_TOLERANCE      = time.Second
+	MIRROR_LIST_TIME_TOLERANCE = time.Minute
+)
+
+var (
+	ErrMirrorStateMismatch = errors.New("State file belongs to another mirror")
+	ErrMirrorPartialList   = errors.New("Source directory listing is incomplete, refusing to delete")
+)
+
+//同步目录时的选项
+type MirrorOptions struct {
+	Reverse   bool     //把本地目录同步到远程目录，默认把远程目录同步到本地目录
+	Delete    bool     //删除目标端中源端没有的文件和目录
+	DryRun    bool     //只生成要执行的操作，不执行
+	Include   []string //只同步匹配的文件，为空时同步所有文件，模式的语法和path.Match相同
+	Exclude   []string //不同步匹配的文件和目录，目录被排除的时候整个目录都不同步
+	StateFile string   //记录同步进度的文件，同步中断后再次执行时从这里继续，不用重新比较两边的目录
+
+	Progress func(action MirrorAction) //每执行完一个操作调用一次，可以为nil
+}
+
+//同步目录时的一个操作
+type MirrorAction struct {
+	Type    MirrorActionType
+	Path    string    //相对于同步的根目录的路径，使用`/`分隔
+	Size    int64     //要传输的文件的大小
+	ModTime time.Time //源端文件的修改时间，下载之后设置为本地文件的修改时间
+	Reason  string    //为什么要执行这个操作
+
+	Done bool  `json:"-"` //是否已经执行成功，包括之前中断的同步中执行的
+	Err  error `json:"-"` //执行失败的原因
+}
+
+//状态文件的第一行，之后每执行成功一个操作追加一行`done 序号`
+type mirrorState struct {
+	Local   string
+	Remote  string
+	Reverse bool
+	Actions []MirrorAction
+}
+
+//同步时比较的文件信息
+type mirrorFile struct {
+	dir     bool
+	size    int64
+	modTime time.Time
+	exact   bool //修改时间是否精确到秒，LIST中的时间不是
+}
+
+//扫描一边的目录树的结果
+type mirrorTree struct {
+	files   map[string]mirrorFile
+	skipped map[string]bool //跳过的符号链接、特殊文件和名字不能使用的文件，另一边同名的文件和目录不会被删除
+	partial bool            //有目录的列表中有不能解析的行，不知道源端有哪些文件
+}
+
+func newMirrorTree() *mirrorTree {
+	return &mirrorTree{files: make(map[string]mirrorFile), skipped: make(map[string]bool)}
+}
+
+//relPath或者它的上级目录是否被跳过
+func (this *mirrorTree) isSkipped(relPath string) bool {
+	for ; relPath != "." && relPath != "/" && relPath != ""; relPath = path.Dir(relPath) {
+		if this.skipped[relPath] {
+			return true
+		}
+	}
+	return false
+}
+
+//按照Include和Exclude过滤文件
+type mirrorFilter struct {
+	include []string
+	exclude []string
+}
+
+//模式既和相对路径比较，也和文件名比较
+func matchAny(patterns []string, relPath string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, relPath); matched {
+			return true
+		}
+		if matched, _ := path.Match(pattern, path.Base(relPath)); matched {
+			return true
+		}
+	}
+	return false
+}
+
+//文件或者目录是否需要同步，Include只对文件起作用，否则目录下的文件就
+//没有机会匹配了
+func (this mirrorFilter) accept(relPath string, dir bool) bool {
+	if matchAny(this.exclude, relPath) {
+		return false
+	}
+	return dir || len(this.include) == 0 || matchAny(this.include, relPath)
+}
+
+//把本地目录localDir和远程目录remoteDir同步。默认以远程目录为准，下载
+//新的或者大小、修改时间不同的文件；Reverse为true的时候以本地目录为准上传。
+//远程文件的修改时间从MLSD的modify获取，只能使用LIST的时候用MDTM获取。
+//下载的文件会设置和远程文件相同的修改时间，上传之后用SetModTime设置
+//远程文件的修改时间，服务器不支持的时候远程文件的修改时间是上传的时间，
+//所以只有本地文件比远程文件新的时候才会再次上传。
+//返回所有要执行的操作，DryRun为true的时候不执行，否则每个操作的Done和Err
+//表示执行的结果。一个操作失败的时候继续执行后面的操作，只有目录不能列出
+//或者连接断开的时候才返回错误。源端跳过的符号链接和特殊文件在目标端的
+//同名文件不会被删除；Delete为true而源端的列表中有不能解析的行的时候，
+//不执行任何操作，返回ErrMirrorPartialList。设置了StateFile的时候，全部操作执行成功
+//之后会删除状态文件，否则下一次可以从状态文件继续
+func (this *Client) Mirror(localDir string, remoteDir string, opts *MirrorOptions) (actions []MirrorAction, err error) {
+	var options MirrorOptions
+	if opts != nil {
+		options = *opts
+	}
+	for _, pattern := range append(append([]string{}, options.Include...), options.Exclude...) {
+		if _, err = path.Match(pattern, ""); err != nil {
+			return
+		}
+	}
+
+	var done = make(map[int]bool)
+	var state *mirrorState
+	if options.StateFile != "" {
+		if state, done, err = loadMirrorState(options.StateFile); err != nil {
+			return
+		}
+		if state != nil && (state.Local != localDir || state.Remote != remoteDir || state.Reverse != options.Reverse) {
+			return nil, ErrMirrorStateMismatch
+		}
+	}
+	if state == nil {
+		state = &mirrorState{Local: localDir, Remote: remoteDir, Reverse: options.Reverse}
+		if state.Actions, err = this.planMirror(localDir, remoteDir, options); err != nil {
+			return
+		}
+		if options.StateFile != "" && !options.DryRun {
+			if err = writeMirrorState(options.StateFile, state); err != nil {
+				return
+			}
+		}
+	}
+	actions = state.Actions
+	for i := range actions {
+		actions[i].Done = done[i]
+	}
+	if options.DryRun {
+		return
+	}
+	return actions, this.runMirror(localDir, remoteDir, actions, options)
+}
+
+//执行还没有完成的操作，每执行成功一个操作就记录到状态文件中
+func (this *Client) runMirror(localDir string, remoteDir string, actions []MirrorAction, options MirrorOptions) (err error) {
+	if options.Reverse {
+		if _, err = this.Mkdir(remoteDir); err != nil && !errors.Is(err, ErrFileUnavailable) {
+			return
+		}
+	} else if err = os.MkdirAll(localDir, 0777); err != nil {
+		return
+	}
+	err = nil
+
+	var stateFile *os.File
+	if options.StateFile != "" {
+		if stateFile, err = os.OpenFile(options.StateFile, os.O_WRONLY|os.O_APPEND, 0); err != nil {
+			return
+		}
+		defer stateFile.Close()
+	}
+	var failed bool
+	for i := range actions {
+		var action = &actions[i]
+		if action.Done {
+			continue
+		}
+		action.Err = this.runMirrorAction(localDir, remoteDir, *action, options.Reverse)
+		if action.Err == nil {
+			action.Done = true
+			if stateFile != nil {
+				fmt.Fprintf(stateFile, "done %d\n", i)
+			}
+		} else {
+			failed = true
+		}
+		if options.Progress != nil {
+			options.Progress(*action)
+		}
+		if isConnectionLost(action.Err) {
+			return action.Err
+		}
+	}
+	if stateFile != nil && !failed {
+		stateFile.Close()
+		err = os.Remove(options.StateFile)
+	}
+	return
+}
+
+//执行一个操作
+func (this *Client) runMirrorAction(localDir string, remoteDir string, action MirrorAction, reverse bool) (err error) {
+	var localPath = filepath.Join(localDir, filepath.FromSlash(action.Path))
+	var remotePath = path.Join(remoteDir, action.Path)
+	switch action.Type {
+	case MIRROR_ACTION_MKDIR:
+		if !reverse {
+			return os.MkdirAll(localPath, 0777)
+		}
+		if _, err = this.Mkdir(remotePath); errors.Is(err, ErrFileUnavailable) {
+			//目录可能已经存在
+			err = nil
+		}
+	case MIRROR_ACTION_TRANSFER:
+		if reverse {
+			if _, _, err = this.UploadFile(localPath, remotePath, nil); err == nil && !action.ModTime.IsZero() {
+				//服务器不能设置修改时间的时候忽略，远程文件保留上传的时间
+				if setErr := this.SetModTime(remotePath, action.ModTime); isConnectionLost(setErr) {
+					err = setErr
+				}
+			}
+			return
+		}
+		if _, err = this.DownloadFile(remotePath, localPath, nil); err == nil && !action.ModTime.IsZero() {
+			err = os.Chtimes(localPath, action.ModTime, action.ModTime)
+		}
+	case MIRROR_ACTION_DELETE:
+		if reverse {
+			return this.Delete(remotePath)
+		}
+		return os.Remove(localPath)
+	case MIRROR_ACTION_RMDIR:
+		if reverse {
+			return this.Rmdir(remotePath)
+		}
+		return os.Remove(localPath)
+	default:
+		err = fmt.Errorf("Unknown mirror action `%s'", action.Type)
+	}
+	return
+}
+
+//比较两边的目录，生成要执行的操作。源端的目录按照路径排序，上级目录
+//总是在下级目录之前创建；删除的时候反过来，先删除目录下的文件
+func (this *Client) planMirror(localDir string, remoteDir string, options MirrorOptions) (actions []MirrorAction, err error) {
+	var filter = mirrorFilter{include: options.Include, exclude: options.Exclude}
+	localTree, err := scanLocalTree(localDir, filter, !options.Reverse)
+	if err != nil {
+		return
+	}
+	remoteTree, err := this.scanRemoteTree(remoteDir, filter, options.Reverse)
+	if err != nil {
+		return
+	}
+	var source, target = remoteTree, localTree
+	if options.Reverse {
+		source, target = localTree, remoteTree
+	}
+	//源端的列表不完整的时候，目标端多出来的文件可能只是没有列出来
+	if options.Delete && source.partial {
+		return nil, ErrMirrorPartialList
+	}
+
+	for _, relPath := range sortedKeys(source.files) {
+		var sourceFile = source.files[relPath]
+		targetFile, exists := target.files[relPath]
+		if sourceFile.dir {
+			if !exists {
+				actions = append(actions, MirrorAction{Type: MIRROR_ACTION_MKDIR, Path: relPath, Reason: "new directory"})
+			}
+			continue
+		}
+		//下载的文件要设置精确的修改时间，上传的时候只在比较时需要
+		var remotePath = path.Join(remoteDir, relPath)
+		if !options.Reverse {
+			err = this.exactModTime(remotePath, &sourceFile)
+		} else if exists && !targetFile.dir && sourceFile.size == targetFile.size {
+			err = this.exactModTime(remotePath, &targetFile)
+		}
+		if err != nil {
+			return
+		}
+		var reason string
+		switch {
+		case !exists:
+			reason = "new file"
+		case targetFile.dir:
+			reason = "directory in the way"
+		case sourceFile.size != targetFile.size:
+			reason = "size differs"
+		case isNewer(sourceFile, targetFile):
+			reason = "newer"
+		}
+		if reason != "" {
+			actions = append(actions, MirrorAction{Type: MIRROR_ACTION_TRANSFER, Path: relPath,
+				Size: sourceFile.size, ModTime: sourceFile.modTime, Reason: reason})
+		}
+	}
+
+	if options.Delete {
+		var targetPaths = sortedKeys(target.files)
+		for i := len(targetPaths) - 1; i >= 0; i-- {
+			var relPath = targetPaths[i]
+			if _, exists := source.files[relPath]; exists || source.isSkipped(relPath) {
+				continue
+			}
+			var actionType = MIRROR_ACTION_DELETE
+			if target.files[relPath].dir {
+				actionType = MIRROR_ACTION_RMDIR
+			}
+			actions = append(actions, MirrorAction{Type: actionType, Path: relPath, Reason: "not in source"})
+		}
+	}
+	return
+}
+
+//远程文件的时间只有LIST中的时间的时候，用MDTM获取精确的时间，服务器不
+//支持MDTM的时候仍然使用LIST中的时间
+func (this *Client) exactModTime(remotePath string, file *mirrorFile) (err error) {
+	if file.exact {
+		return
+	}
+	modTime, err := this.ModTime(remotePath)
+	if err == nil {
+		file.modTime, file.exact = modTime, true
+	} else if !isConnectionLost(err) {
+		err = nil
+	}
+	return
+}
+
+//源端的文件是否比目标端的新，有一边的时间不精确的时候放宽比较的误差
+func isNewer(sourceFile mirrorFile, targetFile mirrorFile) bool {
+	var tolerance = MIRROR_TIME_TOLERANCE
+	if !sourceFile.exact || !targetFile.exact {
+		tolerance = MIRROR_LIST_TIME_TOLERANCE
+	}
+	return sourceFile.modTime.Sub(targetFile.modTime) >= tolerance
+}
+
+func sortedKeys(files map[string]mirrorFile) (keys []string) {
+	for key := range files {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return
+}
+
+//获取本地目录下所有的文件和目录，符号链接和其他特殊文件不同步，记录在
+//skipped中。本地目录不存在的时候，如果allowMissing为true，当作空目录
+func scanLocalTree(localDir string, filter mirrorFilter, allowMissing bool) (tree *mirrorTree, err error) {
+	tree = newMirrorTree()
+	if _, statErr := os.Stat(localDir); os.IsNotExist(statErr) && allowMissing {
+		return
+	}
+	err = filepath.WalkDir(localDir, func(localPath string, entry os.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if localPath == localDir {
+			return nil
+		}
+		rel, relErr := filepath.Rel(localDir, localPath)
+		if relErr != nil {
+			return relErr
+		}
+		var relPath = filepath.ToSlash(rel)
+		if !entry.IsDir() && !entry.Type().IsRegular() {
+			tree.skipped[relPath] = true
+			return nil
+		}
+		if !filter.accept(relPath, entry.IsDir()) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, infoErr := entry.Info()
+		if infoErr != nil {
+			return infoErr
+		}
+		tree.files[relPath] = mirrorFile{dir: entry.IsDir(), size: info.Size(), modTime: info.ModTime(), exact: true}
+		return nil
+	})
+	return
+}
+
+//获取远程目录下所有的文件和目录，符号链接和名字不能使用的文件不同步，
+//记录在skipped中。远程目录不存在的时候，如果allowMissing为true，当作空目录
+func (this *Client) scanRemoteTree(remoteDir string, filter mirrorFilter, allowMissing bool) (tree *mirrorTree, err error) {
+	tree = newMirrorTree()
+	err = this.scanRemoteDir(remoteDir, "", filter, tree, 0)
+	if allowMissing && errors.Is(err, ErrFileUnavailable) {
+		err = nil
+	}
+	return
+}
+
+func (this *Client) scanRemoteDir(remoteDir string, relDir string, filter mirrorFilter, tree *mirrorTree, depth int) (err error) {
+	if depth > TREE_MAX_DEPTH {
+		return ErrTooDeep
+	}
+	entries, err := this.List(remoteDir)
+	var parseErr *ListParseError
+	if errors.As(err, &parseErr) {
+		//不能解析的行不同步，也不知道是哪些文件
+		tree.partial = true
+		err = nil
+	}
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry.Name == "." || entry.Name == ".." {
+			continue
+		}
+		var relPath = path.Join(relDir, entry.Name)
+		if !isPlainName(entry.Name) {
+			//名字中有路径分隔符的时候不知道对应哪个文件
+			tree.partial = true
+			continue
+		}
+		if entry.Type == ENTRY_TYPE_LINK {
+			tree.skipped[relPath] = true
+			continue
+		}
+		var dir = entry.Type == ENTRY_TYPE_DIR
+		if !filter.accept(relPath, dir) {
+			continue
+		}
+		tree.files[relPath] = mirrorFile{dir: dir, size: entry.Size, modTime: entry.ModTime, exact: entry.Facts[MLSX_FACT_MODIFY] != ""}
+		if dir {
+			if err = this.scanRemoteDir(path.Join(remoteDir, entry.Name), relPath, filter, tree, depth+1); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+//读取状态文件，文件不存在的时候state为nil
+func loadMirrorState(stateFile string) (state *mirrorState, done map[int]bool, err error) {
+	done = make(map[int]bool)
+	file, err := os.Open(stateFile)
+	if os.IsNotExist(err) {
+		return nil, done, nil
+	}
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	var reader = bufio.NewReader(file)
+	header, err := reader.ReadBytes('\n')
+	if err != nil {
+		return nil, nil, fmt.Errorf("Invalid state file `%s'", stateFile)
+	}
+	state = &mirrorState{}
+	if err = json.Unmarshal(header, state); err != nil {
+		return nil, nil, fmt.Errorf("Invalid state file `%s': %s", stateFile, err)
+	}
+	var scanner = bufio.NewScanner(reader)
+	for scanner.Scan() {
+		//最后一行可能因为中断只写了一半
+		var fields = strings.Fields(scanner.Text())
+		if len(fields) != 2 || fields[0] != "done" {
+			continue
+		}
+		if index, parseErr := strconv.Atoi(fields[1]); parseErr == nil {
+			done[index] = true
+		}
+	}
+	err = scanner.Err()
+	return
+}
+
+//写入状态文件的第一行，原来的内容会被覆盖
+func writeMirrorState(stateFile string, state *mirrorState) (err error) {
+	header, err := json.Marshal(state)
+	if err != nil {
+		return
+	}
+	return os.WriteFile(stateFile, append(header, '\n'), 0666)
+}
@@ -0,0 +1,197 @@
+package goftp
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//MLSx回复中时间的格式，时间总是UTC时间，秒后面可能还有小数部分
+const MLSX_TIME_LAYOUT = "20060102150405"
+
+//RFC 3659定义的常用信息名
+const (
+	MLSX_FACT_TYPE            = "type"
+	MLSX_FACT_SIZE            = "size"
+	MLSX_FACT_SIZD            = "sizd" //目录所占的空间，一些服务器用来代替目录的size
+	MLSX_FACT_MODIFY          = "modify"
+	MLSX_FACT_PERM            = "perm"
+	MLSX_FACT_UNIQUE          = "unique"
+	MLSX_FACT_UNIX_MODE       = "unix.mode"
+	MLSX_FACT_UNIX_OWNER      = "unix.owner"
+	MLSX_FACT_UNIX_UID        = "unix.uid"
+	MLSX_FACT_UNIX_OWNER_NAME = "unix.ownername" //Pure-FTPd在unix.owner中返回uid，用户名在这里
+)
+
+//获取指定目录(dir为空时为当前目录)下的文件列表，使用RFC 3659的MLSD命令，
+//返回的信息比LIST更可靠。表示目录本身和上级目录的cdir和pdir不会返回
+func (this *Client) Mlsd(dir string) (entries []Entry, err error) {
+	lines, err := this.dataLines(FC_MLSD, dir)
+	if err != nil {
+		return
+	}
+	entries = make([]Entry, 0, len(lines))
+	for _, line := range lines {
+		entry, parseErr := parseMlsxLine(line)
+		if parseErr != nil {
+			return nil, parseErr
+		}
+		var factType = strings.ToLower(entry.Facts[MLSX_FACT_TYPE])
+		if factType == "cdir" || factType == "pdir" {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return
+}
+
+//获取单个文件或者目录的信息，使用RFC 3659的MLST命令，信息通过控制连接
+//返回，不需要建立数据连接
+//
+//	250-Listing path
+//	 type=file;size=1234;modify=20240314120000; /path/name
+//	250 End
+func (this *Client) Mlst(path string) (entry Entry, err error) {
+	var ftpParams = []string{FC_MLST}
+	if path != "" {
+		ftpParams = append(ftpParams, path)
+	}
+	reply, err := this.cmdOK(ftpParams...)
+	if err != nil {
+		return
+	}
+	for _, line := range reply.Lines[1:] {
+		if strings.HasPrefix(line, " ") {
+			return parseMlsxLine(line[1:])
+		}
+	}
+	err = errors.New("No facts in MLST response: " + reply.String())
+	return
+}
+
+//通过`OPTS MLST`选择MLSD和MLST返回哪些信息，facts为空的时候服务器只返回
+//文件名，服务器支持的信息可以从FEAT返回的MLST功能中看到
+func (this *Client) SetMlstFacts(facts ...string) (err error) {
+	var factList string
+	for _, fact := range facts {
+		factList += fact + ";"
+	}
+	var ftpParams = []string{FC_OPTS, FC_MLST}
+	if factList != "" {
+		ftpParams = append(ftpParams, factList)
+	}
+	_, err = this.cmdOK(ftpParams...)
+	return
+}
+
+//解析MLSD和MLST返回的一行，格式是用分号分隔的信息，后面跟着一个空格和
+//文件名，文件名中可以有空格和分号
+//
+//	type=file;size=1234;modify=20240314120000.123;UNIX.mode=0644; name
+func parseMlsxLine(line string) (entry Entry, err error) {
+	entry.Raw = line
+	var index = strings.Index(line, " ")
+	if index == -1 {
+		err = errors.New("Invalid MLSx line: " + line)
+		return
+	}
+	entry.Name = line[index+1:]
+	entry.Facts = make(map[string]string)
+	for _, fact := range strings.Split(line[:index], ";") {
+		var eq = strings.Index(fact, "=")
+		if eq == -1 {
+			continue
+		}
+		entry.Facts[strings.ToLower(fact[:eq])] = fact[eq+1:]
+	}
+
+	var factType = strings.ToLower(entry.Facts[MLSX_FACT_TYPE])
+	switch {
+	case factType == "dir" || factType == "cdir" || factType == "pdir":
+		entry.Type = ENTRY_TYPE_DIR
+	case strings.HasPrefix(factType, "os.unix=slink") || strings.HasPrefix(factType, "os.unix=symlink"):
+		entry.Type = ENTRY_TYPE_LINK
+		//ProFTPD在类型后面加上链接的目标，比如`OS.unix=slink:/etc/hosts`
+		if index := strings.Index(entry.Facts[MLSX_FACT_TYPE], ":"); index != -1 {
+			entry.Target = entry.Facts[MLSX_FACT_TYPE][index+1:]
+		}
+	default:
+		entry.Type = ENTRY_TYPE_FILE
+	}
+	var size = entry.Facts[MLSX_FACT_SIZE]
+	if size == "" {
+		size = entry.Facts[MLSX_FACT_SIZD]
+	}
+	if size != "" {
+		entry.Size, _ = strconv.ParseInt(size, 10, 64)
+	}
+	if modify := entry.Facts[MLSX_FACT_MODIFY]; modify != "" {
+		entry.ModTime, _ = parseMlsxTime(modify)
+	}
+	entry.Perm = entry.Facts[MLSX_FACT_PERM]
+	entry.Unique = entry.Facts[MLSX_FACT_UNIQUE]
+	if mode, parseErr := strconv.ParseUint(entry.Facts[MLSX_FACT_UNIX_MODE], 8, 32); parseErr == nil {
+		entry.UnixMode = unixFileMode(uint32(mode), entry.Type)
+	}
+	for _, fact := range []string{MLSX_FACT_UNIX_OWNER_NAME, MLSX_FACT_UNIX_OWNER, MLSX_FACT_UNIX_UID} {
+		if owner := entry.Facts[fact]; owner != "" {
+			entry.Owner = owner
+			break
+		}
+	}
+	return
+}
+
+//解析MLSx格式的时间，YYYYMMDDHHMMSS[.sss]，总是UTC时间
+func parseMlsxTime(value string) (t time.Time, err error) {
+	var fraction string
+	if index := strings.Index(value, "."); index != -1 {
+		value, fraction = value[:index], value[index:]
+	}
+	t, err = time.ParseInLocation(MLSX_TIME_LAYOUT, value, time.UTC)
+	if err != nil || fraction == "" {
+		return
+	}
+	if seconds, parseErr := strconv.ParseFloat("0"+fraction, 64); parseErr == nil {
+		t = t.Add(time.Duration(seconds * float64(time.Second)))
+	}
+	return
+}
+
+//把os.FileMode转换为Unix的八进制权限，和unixFileMode相反，不包括文件类型
+func unixModeBits(fileMode os.FileMode) (mode uint32) {
+	mode = uint32(fileMode.Perm())
+	if fileMode&os.ModeSetuid != 0 {
+		mode |= 04000
+	}
+	if fileMode&os.ModeSetgid != 0 {
+		mode |= 02000
+	}
+	if fileMode&os.ModeSticky != 0 {
+		mode |= 01000
+	}
+	return
+}
+
+//把Unix的八进制权限转换为os.FileMode，同时加上文件类型
+func unixFileMode(mode uint32, entryType EntryType) (fileMode os.FileMode) {
+	fileMode = os.FileMode(mode & 0777)
+	if mode&04000 != 0 {
+		fileMode |= os.ModeSetuid
+	}
+	if mode&02000 != 0 {
+		fileMode |= os.ModeSetgid
+	}
+	if mode&01000 != 0 {
+		fileMode |= os.ModeSticky
+	}
+	switch entryType {
+	case ENTRY_TYPE_DIR:
+		fileMode |= os.ModeDir
+	case ENTRY_TYPE_LINK:
+		fileMode |= os.ModeSymlink
+	}
+	return
+}
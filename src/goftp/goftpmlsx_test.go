@@ -0,0 +1,131 @@
+package goftp
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestParseMlsxLine(t *testing.T) {
+	var tests = []struct {
+		name  string
+		line  string
+		entry Entry
+	}{
+		{"file", "type=file;size=1234;modify=20240314120000;perm=adfrw;unique=801U2; readme.txt",
+			Entry{Name: "readme.txt", Type: ENTRY_TYPE_FILE, Size: 1234,
+				ModTime: time.Date(2024, 3, 14, 12, 0, 0, 0, time.UTC), Perm: "adfrw", Unique: "801U2"}},
+		//信息名不区分大小写，文件名中可以有空格和分号
+		{"name with spaces", "Type=file;Size=5;UNIX.mode=0644;UNIX.owner=1000; my file; v2.txt",
+			Entry{Name: "my file; v2.txt", Type: ENTRY_TYPE_FILE, Size: 5, UnixMode: 0644, Owner: "1000"}},
+		{"dir", "type=dir;sizd=4096;UNIX.mode=0755; src",
+			Entry{Name: "src", Type: ENTRY_TYPE_DIR, Size: 4096, UnixMode: os.ModeDir | 0755}},
+		{"cdir", "type=cdir;perm=el; /home/user", Entry{Name: "/home/user", Type: ENTRY_TYPE_DIR, Perm: "el"}},
+		{"pdir", "type=pdir; ..", Entry{Name: "..", Type: ENTRY_TYPE_DIR}},
+		{"slink", "type=OS.unix=slink:/etc/hosts; hosts", Entry{Name: "hosts", Type: ENTRY_TYPE_LINK, Target: "/etc/hosts"}},
+		{"symlink", "type=OS.unix=symlink;UNIX.mode=0777; latest",
+			Entry{Name: "latest", Type: ENTRY_TYPE_LINK, UnixMode: os.ModeSymlink | 0777}},
+		{"setuid", "type=file;UNIX.mode=4755; passwd", Entry{Name: "passwd", Type: ENTRY_TYPE_FILE, UnixMode: os.ModeSetuid | 0755}},
+		//Pure-FTPd的unix.owner是uid，用户名在unix.ownername中
+		{"owner name", "type=file;UNIX.owner=1000;UNIX.ownername=alice; a", Entry{Name: "a", Type: ENTRY_TYPE_FILE, Owner: "alice"}},
+		{"no facts", " bare", Entry{Name: "bare", Type: ENTRY_TYPE_FILE}},
+		{"invalid values", "type=file;size=abc;modify=yesterday;UNIX.mode=999; a", Entry{Name: "a", Type: ENTRY_TYPE_FILE}},
+	}
+	for _, test := range tests {
+		entry, err := parseMlsxLine(test.line)
+		if err != nil {
+			t.Errorf("%s: parseMlsxLine() error = %v", test.name, err)
+			continue
+		}
+		if entry.Raw != test.line {
+			t.Errorf("%s: Raw = %q, want %q", test.name, entry.Raw, test.line)
+		}
+		var want = test.entry
+		if entry.Name != want.Name || entry.Type != want.Type || entry.Size != want.Size ||
+			!entry.ModTime.Equal(want.ModTime) || entry.Perm != want.Perm || entry.Unique != want.Unique ||
+			entry.UnixMode != want.UnixMode || entry.Owner != want.Owner || entry.Target != want.Target {
+			t.Errorf("%s: parseMlsxLine() = %+v, want %+v", test.name, entry, want)
+		}
+	}
+}
+
+func TestParseMlsxLineFacts(t *testing.T) {
+	entry, err := parseMlsxLine("Type=file;Size=5;UNIX.mode=0644;broken;empty=; a")
+	if err != nil {
+		t.Fatalf("parseMlsxLine() error = %v", err)
+	}
+	var want = map[string]string{"type": "file", "size": "5", "unix.mode": "0644", "empty": ""}
+	if len(entry.Facts) != len(want) {
+		t.Errorf("Facts = %q, want %q", entry.Facts, want)
+	}
+	for key, value := range want {
+		if got, ok := entry.Facts[key]; !ok || got != value {
+			t.Errorf("Facts[%q] = %q, want %q", key, got, value)
+		}
+	}
+}
+
+func TestParseMlsxLineError(t *testing.T) {
+	for _, line := range []string{"", "type=file;size=5;name"} {
+		if _, err := parseMlsxLine(line); err == nil {
+			t.Errorf("parseMlsxLine(%q) error = nil, want error", line)
+		}
+	}
+}
+
+func TestParseMlsxTime(t *testing.T) {
+	var tests = []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"20240314120000", time.Date(2024, 3, 14, 12, 0, 0, 0, time.UTC), false},
+		{"20240314120000.5", time.Date(2024, 3, 14, 12, 0, 0, 500000000, time.UTC), false},
+		{"20240314120000.123", time.Date(2024, 3, 14, 12, 0, 0, 123000000, time.UTC), false},
+		{"19991231235959", time.Date(1999, 12, 31, 23, 59, 59, 0, time.UTC), false},
+		{"2024031412", time.Time{}, true},
+		{"20241314120000", time.Time{}, true},
+		{"", time.Time{}, true},
+	}
+	for _, test := range tests {
+		got, err := parseMlsxTime(test.value)
+		if (err != nil) != test.wantErr {
+			t.Errorf("parseMlsxTime(%q) error = %v, wantErr %v", test.value, err, test.wantErr)
+			continue
+		}
+		if test.wantErr {
+			continue
+		}
+		//小数部分用浮点数换算，允许一微秒以内的误差
+		if diff := got.Sub(test.want); diff < -time.Microsecond || diff > time.Microsecond || got.Location() != time.UTC {
+			t.Errorf("parseMlsxTime(%q) = %v, want %v", test.value, got, test.want)
+		}
+	}
+}
+
+func TestUnixFileMode(t *testing.T) {
+	var tests = []struct {
+		mode      uint32
+		entryType EntryType
+		want      os.FileMode
+	}{
+		{0644, ENTRY_TYPE_FILE, 0644},
+		{0755, ENTRY_TYPE_DIR, os.ModeDir | 0755},
+		{0777, ENTRY_TYPE_LINK, os.ModeSymlink | 0777},
+		{04755, ENTRY_TYPE_FILE, os.ModeSetuid | 0755},
+		{02755, ENTRY_TYPE_DIR, os.ModeDir | os.ModeSetgid | 0755},
+		{01777, ENTRY_TYPE_DIR, os.ModeDir | os.ModeSticky | 0777},
+		//文件类型的位不属于权限，要被忽略
+		{0100644, ENTRY_TYPE_FILE, 0644},
+	}
+	for _, test := range tests {
+		var got = unixFileMode(test.mode, test.entryType)
+		if got != test.want {
+			t.Errorf("unixFileMode(%o, %d) = %v, want %v", test.mode, test.entryType, got, test.want)
+		}
+		//去掉文件类型之后转换回来应该得到原来的权限
+		if bits := unixModeBits(got); bits != test.mode&07777 {
+			t.Errorf("unixModeBits(%v) = %o, want %o", got, bits, test.mode&07777)
+		}
+	}
+}
@@ -0,0 +1,413 @@
+package goftp
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+//处理多个文件的命令(mget、mput、mdelete和mdir)的执行结果，一个文件失败
+//的时候继续处理后面的文件，最后打印汇总
+type multiResult struct {
+	cmdName   string
+	succeeded int
+	skipped   int
+	failed    []string //失败的文件和原因
+	yesToAll  bool     //询问时回答了a，后面的文件不再询问
+}
+
+//记录一个文件的处理结果，服务器的回复已经打印过了，其他错误在这里打印
+func (this *multiResult) add(name string, err error) {
+	if err == nil {
+		this.succeeded++
+		return
+	}
+	printCmdError(err)
+	this.failed = append(this.failed, name+": "+err.Error())
+}
+
+//打印汇总，只处理了一个文件并且成功的时候不打印
+func (this *multiResult) summary() {
+	if this.succeeded+this.skipped+len(this.failed) <= 1 && len(this.failed) == 0 {
+		return
+	}
+	fmt.Printf("%s: %d succeeded, %d failed, %d skipped\n", this.cmdName, this.succeeded, len(this.failed), this.skipped)
+	for _, failure := range this.failed {
+		fmt.Println("  " + failure)
+	}
+}
+
+//打印递归传输中一个文件或者目录的结果，同时计入汇总，目录不计入成功的数量
+func (this *multiResult) addTree(result TreeResult) {
+	var name = result.Path
+	if result.Dir {
+		name += "/"
+	}
+	switch {
+	case result.Skipped:
+		this.skipped++
+		fmt.Printf("skipped %s: %s\n", name, result.Err)
+	case result.Err != nil:
+		this.failed = append(this.failed, name+": "+result.Err.Error())
+		fmt.Printf("failed  %s: %s\n", name, result.Err)
+	case result.Dir:
+		fmt.Printf("ok      %s\n", name)
+	default:
+		this.succeeded++
+		fmt.Printf("ok      %s (%d bytes)\n", name, result.Size)
+	}
+}
+
+//打印同步目录时执行的一个操作的结果，同时计入汇总
+func (this *multiResult) addMirror(action MirrorAction) {
+	if action.Err != nil {
+		this.failed = append(this.failed, string(action.Type)+" "+action.Path+": "+action.Err.Error())
+		fmt.Printf("failed  %s %s: %s\n", action.Type, action.Path, action.Err)
+		return
+	}
+	this.succeeded++
+	fmt.Printf("ok      %s\n", formatMirrorAction(action))
+}
+
+//同步目录的操作的描述，比如`transfer a/b.txt (newer, 1024 bytes)`
+func formatMirrorAction(action MirrorAction) string {
+	if action.Type == MIRROR_ACTION_TRANSFER {
+		return fmt.Sprintf("%s %s (%s, %d bytes)", action.Type, action.Path, action.Reason, action.Size)
+	}
+	return fmt.Sprintf("%s %s (%s)", action.Type, action.Path, action.Reason)
+}
+
+//同步本地目录和远程目录，默认以远程目录为准下载，-R表示以本地目录为准
+//上传，-delete删除多余的文件，-dry-run只打印要执行的操作，-include和
+//-exclude可以指定多次，-state指定记录进度的文件
+func (this *GoFtpClientCmd) mirror() (err error) {
+	if err = this.checkConnected(); err != nil {
+		return
+	}
+	var opts = &MirrorOptions{}
+	var params = this.Params
+	for len(params) > 0 && strings.HasPrefix(params[0], "-") {
+		switch {
+		case params[0] == "-R":
+			opts.Reverse = true
+		case params[0] == "-delete":
+			opts.Delete = true
+		case params[0] == "-dry-run":
+			opts.DryRun = true
+		case params[0] == "-include" && len(params) > 1:
+			opts.Include = append(opts.Include, params[1])
+			params = params[1:]
+		case params[0] == "-exclude" && len(params) > 1:
+			opts.Exclude = append(opts.Exclude, params[1])
+			params = params[1:]
+		case params[0] == "-state" && len(params) > 1:
+			opts.StateFile = this.localPath(params[1])
+			params = params[1:]
+		default:
+			this.cmdUsage(this.Name)
+			return
+		}
+		params = params[1:]
+	}
+	if len(params) != 2 {
+		this.cmdUsage(this.Name)
+		return
+	}
+	var localDir, remoteDir = params[0], params[1]
+	fmt.Println("local:", localDir, "remote:", remoteDir)
+
+	var result = &multiResult{cmdName: this.Name}
+	opts.Progress = result.addMirror
+	actions, err := this.FtpClient.Mirror(this.localPath(localDir), remoteDir, opts)
+	if err != nil {
+		return
+	}
+	var pending int
+	for _, action := range actions {
+		if action.Done {
+			continue
+		}
+		pending++
+		if opts.DryRun {
+			fmt.Println(formatMirrorAction(action))
+		}
+	}
+	switch {
+	case opts.DryRun:
+		fmt.Printf("%s: %d action(s) planned\n", this.Name, pending)
+	case len(actions) == 0:
+		fmt.Printf("%s: nothing to do\n", this.Name)
+	default:
+		if resumed := len(actions) - result.succeeded - len(result.failed); resumed > 0 {
+			fmt.Printf("%s: %d action(s) already done before\n", this.Name, resumed)
+		}
+		result.summary()
+	}
+	return
+}
+
+//prompt打开并且是交互模式的时候，询问是否处理这个文件，stop表示不再处理
+//后面的文件
+func (this *GoFtpClientCmd) confirm(result *multiResult, name string) (proceed bool, stop bool) {
+	if this.NoPrompt || result.yesToAll || !isInteractive() {
+		return true, false
+	}
+	for {
+		answer, err := readInput(fmt.Sprintf("%s %s [anqy?]? ", result.cmdName, name))
+		if err != nil {
+			return false, true
+		}
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "", "y", "yes":
+			return true, false
+		case "n", "no":
+			result.skipped++
+			return false, false
+		case "a":
+			result.yesToAll = true
+			return true, false
+		case "q":
+			return false, true
+		default:
+			fmt.Println("a  answer yes to all remaining files")
+			fmt.Println("n  skip this file")
+			fmt.Println("q  stop, skip all remaining files")
+			fmt.Println("y  answer yes to this file")
+		}
+	}
+}
+
+//展开远程文件名中的通配符，glob关闭的时候原样使用，没有匹配的模式记为失败
+func (this *GoFtpClientCmd) expandRemote(result *multiResult, patterns []string) (files []string, err error) {
+	for _, pattern := range patterns {
+		if this.NoGlob {
+			files = append(files, pattern)
+			continue
+		}
+		matches, globErr := this.FtpClient.Glob(pattern)
+		if isConnectionLost(globErr) {
+			return nil, globErr
+		}
+		if globErr == nil && len(matches) == 0 {
+			globErr = fmt.Errorf("`%s': No match", pattern)
+		}
+		if globErr != nil {
+			result.add(pattern, globErr)
+			continue
+		}
+		files = append(files, matches...)
+	}
+	return
+}
+
+//展开本地文件名中的通配符，相对路径都相对于本地工作目录
+func (this *GoFtpClientCmd) expandLocal(result *multiResult, patterns []string) (files []string) {
+	for _, pattern := range patterns {
+		if this.NoGlob {
+			files = append(files, pattern)
+			continue
+		}
+		matches, err := filepath.Glob(this.localPath(pattern))
+		if err == nil && len(matches) == 0 {
+			err = fmt.Errorf("`%s': No match", pattern)
+		}
+		if err != nil {
+			result.add(pattern, err)
+			continue
+		}
+		for _, match := range matches {
+			//相对路径的模式显示相对路径
+			if !filepath.IsAbs(pattern) {
+				if rel, relErr := filepath.Rel(this.LocalWorkDir, match); relErr == nil {
+					match = rel
+				}
+			}
+			files = append(files, match)
+		}
+	}
+	return
+}
+
+//下载多个远程文件到本地工作目录，支持通配符
+func (this *GoFtpClientCmd) mget() (err error) {
+	if err = this.checkConnected(); err != nil {
+		return
+	}
+	if len(this.Params) == 0 {
+		this.cmdUsage(this.Name)
+		return
+	}
+	var result = &multiResult{cmdName: this.Name}
+	defer result.summary()
+	remoteFiles, err := this.expandRemote(result, this.Params)
+	if err != nil {
+		return
+	}
+	for _, remoteFile := range remoteFiles {
+		proceed, stop := this.confirm(result, remoteFile)
+		if stop {
+			break
+		}
+		if !proceed {
+			continue
+		}
+		var getErr = this.getFile(remoteFile, path.Base(remoteFile), nil)
+		if isConnectionLost(getErr) {
+			return getErr
+		}
+		result.add(remoteFile, getErr)
+	}
+	return
+}
+
+//上传多个本地文件到远程的当前目录，支持通配符，目录会被跳过
+func (this *GoFtpClientCmd) mput() (err error) {
+	if err = this.checkConnected(); err != nil {
+		return
+	}
+	if len(this.Params) == 0 {
+		this.cmdUsage(this.Name)
+		return
+	}
+	var result = &multiResult{cmdName: this.Name}
+	defer result.summary()
+	for _, localFile := range this.expandLocal(result, this.Params) {
+		if info, statErr := os.Stat(this.localPath(localFile)); statErr == nil && info.IsDir() {
+			fmt.Printf("%s: %s: not a plain file.\n", this.Name, localFile)
+			result.skipped++
+			continue
+		}
+		proceed, stop := this.confirm(result, localFile)
+		if stop {
+			break
+		}
+		if !proceed {
+			continue
+		}
+		var putErr = this.putFile(FC_STOR, localFile, filepath.Base(localFile), &TransferOptions{})
+		if isConnectionLost(putErr) {
+			return putErr
+		}
+		result.add(localFile, putErr)
+	}
+	return
+}
+
+//删除多个远程文件，支持通配符
+func (this *GoFtpClientCmd) mdelete() (err error) {
+	if err = this.checkConnected(); err != nil {
+		return
+	}
+	if len(this.Params) == 0 {
+		this.cmdUsage(this.Name)
+		return
+	}
+	var result = &multiResult{cmdName: this.Name}
+	defer result.summary()
+	remoteFiles, err := this.expandRemote(result, this.Params)
+	if err != nil {
+		return
+	}
+	for _, remoteFile := range remoteFiles {
+		proceed, stop := this.confirm(result, remoteFile)
+		if stop {
+			break
+		}
+		if !proceed {
+			continue
+		}
+		var deleteErr = this.FtpClient.Delete(remoteFile)
+		if isConnectionLost(deleteErr) {
+			return deleteErr
+		}
+		result.add(remoteFile, deleteErr)
+	}
+	return
+}
+
+//打印多个远程目录的详细内容，有两个以上参数的时候最后一个参数是保存结果
+//的本地文件，为`-`时输出到标准输出
+func (this *GoFtpClientCmd) mdir() (err error) {
+	if err = this.checkConnected(); err != nil {
+		return
+	}
+	var remoteDirs = this.Params
+	if len(remoteDirs) == 0 {
+		this.cmdUsage(this.Name)
+		return
+	}
+	var output = os.Stdout
+	if len(remoteDirs) > 1 {
+		var outputName = remoteDirs[len(remoteDirs)-1]
+		remoteDirs = remoteDirs[:len(remoteDirs)-1]
+		if outputName != "-" {
+			var outputPath = this.localPath(outputName)
+			outputFile, createErr := os.Create(outputPath)
+			if createErr != nil {
+				return fmt.Errorf("Can't access `%s': No such file or directory", outputPath)
+			}
+			defer outputFile.Close()
+			output = outputFile
+		}
+	}
+
+	var result = &multiResult{cmdName: this.Name}
+	defer result.summary()
+	remoteDirs, err = this.expandRemote(result, remoteDirs)
+	if err != nil {
+		return
+	}
+	var bWriter = bufio.NewWriter(output)
+	for _, remoteDir := range remoteDirs {
+		proceed, stop := this.confirm(result, remoteDir)
+		if stop {
+			break
+		}
+		if !proceed {
+			continue
+		}
+		lines, listErr := this.FtpClient.dataLines(FC_LIST, remoteDir)
+		if isConnectionLost(listErr) {
+			return listErr
+		}
+		for _, line := range lines {
+			bWriter.WriteString(line + "\n")
+		}
+		bWriter.Flush()
+		result.add(remoteDir, listErr)
+	}
+	return
+}
+
+//切换多个文件的命令是否展开通配符
+func (this *GoFtpClientCmd) glob() (err error) {
+	if len(this.Params) > 0 {
+		this.cmdUsage(this.Name)
+		return
+	}
+	this.NoGlob = !this.NoGlob
+	if this.NoGlob {
+		fmt.Println("Globbing off.")
+	} else {
+		fmt.Println("Globbing on.")
+	}
+	return
+}
+
+//切换多个文件的命令是否对每个文件询问
+func (this *GoFtpClientCmd) prompt() (err error) {
+	if len(this.Params) > 0 {
+		this.cmdUsage(this.Name)
+		return
+	}
+	this.NoPrompt = !this.NoPrompt
+	if this.NoPrompt {
+		fmt.Println("Interactive mode off.")
+	} else {
+		fmt.Println("Interactive mode on.")
+	}
+	return
+}
@@ -0,0 +1,300 @@
+package goftp
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//hash命令默认每传输多少字节打印一个#
+const HASH_MARK_DEFAULT_SIZE int64 = 1024
+
+//进度条的宽度和刷新间隔
+const (
+	PROGRESS_BAR_WIDTH    = 30
+	PROGRESS_BAR_INTERVAL = 200 * time.Millisecond
+)
+
+//文件传输的进度
+type Progress struct {
+	Offset      int64         //断点续传时之前已经传输的字节数，不计入Transferred
+	Transferred int64         //这一次传输的字节数
+	Total       int64         //文件的总字节数，不知道的时候为-1
+	Elapsed     time.Duration //开始传输之后经过的时间
+}
+
+//每次读写数据之后调用，同一个传输中的调用不会同时发生
+type ProgressFunc func(progress Progress)
+
+//平均每秒传输的字节数
+func (this Progress) Rate() float64 {
+	if this.Elapsed <= 0 {
+		return 0
+	}
+	return float64(this.Transferred) / this.Elapsed.Seconds()
+}
+
+//已经完成的百分比，不知道总字节数的时候返回-1
+func (this Progress) Percent() float64 {
+	if this.Total <= 0 {
+		return -1
+	}
+	var percent = float64(this.Offset+this.Transferred) * 100 / float64(this.Total)
+	if percent > 100 {
+		percent = 100
+	}
+	return percent
+}
+
+//按照平均速度估计的剩余时间，不知道总字节数或者还没有数据的时候返回-1
+func (this Progress) Remaining() time.Duration {
+	var rate = this.Rate()
+	if this.Total < 0 || rate <= 0 {
+		return -1
+	}
+	var left = this.Total - this.Offset - this.Transferred
+	if left < 0 {
+		left = 0
+	}
+	return time.Duration(float64(left) / rate * float64(time.Second))
+}
+
+//统计一次传输的进度，分段下载的时候多个goroutine共享一个
+type progressTracker struct {
+	mutex    sync.Mutex
+	start    time.Time
+	progress Progress
+	callback ProgressFunc
+}
+
+//callback为nil的时候返回nil，nil的progressTracker不统计进度
+func newProgressTracker(offset int64, total int64, callback ProgressFunc) *progressTracker {
+	if callback == nil {
+		return nil
+	}
+	return &progressTracker{
+		start:    time.Now(),
+		progress: Progress{Offset: offset, Total: total},
+		callback: callback,
+	}
+}
+
+func (this *progressTracker) add(n int) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.progress.Transferred += int64(n)
+	this.progress.Elapsed = time.Since(this.start)
+	this.callback(this.progress)
+}
+
+func (this *progressTracker) reader(reader io.Reader) io.Reader {
+	if this == nil {
+		return reader
+	}
+	return &ProgressReader{reader: reader, tracker: this}
+}
+
+func (this *progressTracker) writer(writer io.Writer) io.Writer {
+	if this == nil {
+		return writer
+	}
+	return &ProgressWriter{writer: writer, tracker: this}
+}
+
+//在读取数据的时候报告进度的io.Reader
+type ProgressReader struct {
+	reader  io.Reader
+	tracker *progressTracker
+}
+
+//从reader读取数据，每次读到数据之后用callback报告进度，total是预计的
+//总字节数，不知道的时候为-1
+func NewProgressReader(reader io.Reader, total int64, callback ProgressFunc) *ProgressReader {
+	return &ProgressReader{reader: reader, tracker: newProgressTracker(0, total, callback)}
+}
+
+func (this *ProgressReader) Read(p []byte) (n int, err error) {
+	n, err = this.reader.Read(p)
+	if n > 0 && this.tracker != nil {
+		this.tracker.add(n)
+	}
+	return
+}
+
+//在写入数据的时候报告进度的io.Writer
+type ProgressWriter struct {
+	writer  io.Writer
+	tracker *progressTracker
+}
+
+//把数据写到writer中，每次写入之后用callback报告进度，total是预计的
+//总字节数，不知道的时候为-1
+func NewProgressWriter(writer io.Writer, total int64, callback ProgressFunc) *ProgressWriter {
+	return &ProgressWriter{writer: writer, tracker: newProgressTracker(0, total, callback)}
+}
+
+func (this *ProgressWriter) Write(p []byte) (n int, err error) {
+	n, err = this.writer.Write(p)
+	if n > 0 && this.tracker != nil {
+		this.tracker.add(n)
+	}
+	return
+}
+
+/*
+下面是交互模式中显示传输进度的部分，打开progress的时候显示一行进度条，
+否则打开hash的时候每传输一块数据打印一个#
+*/
+
+//交互模式的标准输出，服务器的回复也写到这里。进度显示在一行的中间的时候，
+//先换行再输出服务器的回复
+type consoleWriter struct {
+	mutex   sync.Mutex
+	pending bool
+}
+
+var console = &consoleWriter{}
+
+func (this *consoleWriter) Write(p []byte) (n int, err error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.endLine()
+	return os.Stdout.Write(p)
+}
+
+//输出进度，不换行
+func (this *consoleWriter) printProgress(text string) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	fmt.Print(text)
+	this.pending = true
+}
+
+//结束进度所在的一行
+func (this *consoleWriter) endLine() {
+	if this.pending {
+		fmt.Println()
+		this.pending = false
+	}
+}
+
+//在终端上显示一次传输的进度
+type transferDisplay struct {
+	hashMarkSize int64
+	progressBar  bool
+	hashMarks    int64
+	lastDraw     time.Time
+}
+
+//hash和progress都没有打开的时候返回nil
+func (this *GoFtpClientCmd) newTransferDisplay() *transferDisplay {
+	if this.HashMarkSize <= 0 && !this.ShowProgress {
+		return nil
+	}
+	return &transferDisplay{hashMarkSize: this.HashMarkSize, progressBar: this.ShowProgress}
+}
+
+//用来设置TransferOptions.Progress，nil的transferDisplay返回nil
+func (this *transferDisplay) callback() ProgressFunc {
+	if this == nil {
+		return nil
+	}
+	return this.update
+}
+
+func (this *transferDisplay) update(progress Progress) {
+	if this.progressBar {
+		var done = progress.Total >= 0 && progress.Offset+progress.Transferred >= progress.Total
+		if !done && time.Since(this.lastDraw) < PROGRESS_BAR_INTERVAL {
+			return
+		}
+		this.lastDraw = time.Now()
+		console.printProgress("\r" + formatProgressBar(progress))
+		return
+	}
+	var marks = progress.Transferred / this.hashMarkSize
+	if marks > this.hashMarks {
+		console.printProgress(strings.Repeat("#", int(marks-this.hashMarks)))
+		this.hashMarks = marks
+	}
+}
+
+//传输结束之后换行，进度已经被服务器的回复结束的时候什么都不做
+func (this *transferDisplay) finish() {
+	if this != nil {
+		console.mutex.Lock()
+		console.endLine()
+		console.mutex.Unlock()
+	}
+}
+
+//进度条的格式为` 45% [=============>                ] 1.20 MB/2.66 MB 1.05 MB/s ETA 00:02`，
+//不知道总字节数的时候只显示传输的字节数和速度
+func formatProgressBar(progress Progress) string {
+	var transferred = progress.Offset + progress.Transferred
+	var rate = formatBytes(progress.Rate()) + "/s"
+	var percent = progress.Percent()
+	if percent < 0 {
+		return fmt.Sprintf("%s %s", formatBytes(float64(transferred)), rate)
+	}
+	var filled = int(percent * PROGRESS_BAR_WIDTH / 100)
+	var bar = strings.Repeat("=", filled)
+	if filled < PROGRESS_BAR_WIDTH {
+		bar += ">" + strings.Repeat(" ", PROGRESS_BAR_WIDTH-filled-1)
+	}
+	var eta = "--:--"
+	if remaining := progress.Remaining(); remaining >= 0 {
+		var seconds = int64(remaining.Seconds() + 0.5)
+		if seconds >= 3600 {
+			eta = fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+		} else {
+			eta = fmt.Sprintf("%02d:%02d", seconds/60, seconds%60)
+		}
+	}
+	return fmt.Sprintf("%3.0f%% [%s] %s/%s %s ETA %s ", percent, bar,
+		formatBytes(float64(transferred)), formatBytes(float64(progress.Total)), rate, eta)
+}
+
+//切换传输时是否打印#，参数是每个#代表的字节数
+func (this *GoFtpClientCmd) hash() (err error) {
+	if len(this.Params) > 1 {
+		this.cmdUsage(this.Name)
+		return
+	}
+	if len(this.Params) == 1 {
+		size, parseErr := strconv.ParseInt(this.Params[0], 10, 64)
+		if parseErr != nil || size <= 0 {
+			return fmt.Errorf("hash: Invalid size `%s'", this.Params[0])
+		}
+		this.HashMarkSize = size
+	} else if this.HashMarkSize > 0 {
+		this.HashMarkSize = 0
+	} else {
+		this.HashMarkSize = HASH_MARK_DEFAULT_SIZE
+	}
+	if this.HashMarkSize > 0 {
+		fmt.Printf("Hash mark printing on (%d bytes/hash mark).\n", this.HashMarkSize)
+	} else {
+		fmt.Println("Hash mark printing off.")
+	}
+	return
+}
+
+//切换传输时是否显示进度条
+func (this *GoFtpClientCmd) progress() (err error) {
+	if len(this.Params) > 0 {
+		this.cmdUsage(this.Name)
+		return
+	}
+	this.ShowProgress = !this.ShowProgress
+	if this.ShowProgress {
+		fmt.Println("Progress bar on.")
+	} else {
+		fmt.Println("Progress bar off.")
+	}
+	return
+}
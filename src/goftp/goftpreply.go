@@ -0,0 +1,98 @@
+package goftp
+
+import (
+	"bufio"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+//ftp服务器的一条回复，按照RFC 959的规定，回复可以是单行的
+//
+//	xyz text
+//
+//也可以是多行的，第一行的回复码后面跟着`-`，直到出现一行以相同的
+//回复码加上空格开头的行为止
+//
+//	xyz-first line
+//	 middle line
+//	xyz last line
+type Reply struct {
+	Code  int      //回复码
+	Lines []string //回复的所有行，不包括行尾的换行
+}
+
+//回复的文本内容，去掉了首行和末行的回复码，多行之间用换行分隔
+func (this Reply) Message() string {
+	var lines = make([]string, len(this.Lines))
+	for i, line := range this.Lines {
+		if (i == 0 || i == len(this.Lines)-1) && len(line) >= 4 && isReplyCode(line[:3]) {
+			line = line[4:]
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+//回复的原始内容，多行之间用换行分隔
+func (this Reply) String() string {
+	return strings.Join(this.Lines, "\n")
+}
+
+//判断是否为三位数字的回复码
+func isReplyCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+//从控制连接中读取一条完整的回复，一次Read读到的数据可能只是回复的
+//一部分，也可能包含多条回复，所以这里按行读取，直到回复结束为止
+func readReply(reader *bufio.Reader) (reply Reply, err error) {
+	line, err := readReplyLine(reader)
+	if err != nil {
+		return
+	}
+	if len(line) < 3 || !isReplyCode(line[:3]) || (len(line) > 3 && line[3] != ' ' && line[3] != '-') {
+		err = errors.New("Invalid response from server: " + line)
+		return
+	}
+	reply.Code, _ = strconv.Atoi(line[:3])
+	reply.Lines = append(reply.Lines, line)
+	if len(line) == 3 || line[3] == ' ' {
+		return
+	}
+
+	//多行回复，直到读到以相同回复码加空格开头的行为止，中间的行
+	//即使以数字开头也只是回复的内容
+	var terminator = line[:3] + " "
+	for {
+		line, err = readReplyLine(reader)
+		if err != nil {
+			return
+		}
+		reply.Lines = append(reply.Lines, line)
+		if strings.HasPrefix(line, terminator) || line == terminator[:3] {
+			return
+		}
+	}
+}
+
+//读取一行并去掉行尾的换行
+func readReplyLine(reader *bufio.Reader) (line string, err error) {
+	line, err = reader.ReadString('\n')
+	if err != nil {
+		if line != "" {
+			err = errors.New("Incomplete response from server: " + line)
+		}
+		return
+	}
+	line = strings.TrimRight(line, "\r\n")
+	return
+}
@@ -0,0 +1,90 @@
+package goftp
+
+import (
+	"bufio"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReadReply(t *testing.T) {
+	var tests = []struct {
+		name    string
+		input   string
+		code    int
+		lines   []string
+		wantErr bool
+	}{
+		{"single line", "220 Service ready\r\n", 220, []string{"220 Service ready"}, false},
+		{"code only", "200\r\n", 200, []string{"200"}, false},
+		{"bare LF", "200 ok\n", 200, []string{"200 ok"}, false},
+		{"multi line", "211-Features:\r\n EPSV\r\n MDTM\r\n211 End\r\n", 211,
+			[]string{"211-Features:", " EPSV", " MDTM", "211 End"}, false},
+		//中间的行以数字开头也只是回复的内容，只有相同的回复码加空格才结束
+		{"numeric middle lines", "150-first\r\n226 other code\r\n150-dash\r\n150x\r\n150 done\r\n", 150,
+			[]string{"150-first", "226 other code", "150-dash", "150x", "150 done"}, false},
+		{"bare code terminator", "230-welcome\r\n230\r\n", 230, []string{"230-welcome", "230"}, false},
+		{"empty middle line", "214-help\r\n\r\n214 end\r\n", 214, []string{"214-help", "", "214 end"}, false},
+		{"invalid code", "hello\r\n", 0, nil, true},
+		{"invalid separator", "220xready\r\n", 0, nil, true},
+		{"short line", "22\r\n", 0, nil, true},
+		{"incomplete line", "220 no newline", 0, nil, true},
+		{"unterminated multi line", "211-Features:\r\n EPSV\r\n", 211, nil, true},
+		{"empty input", "", 0, nil, true},
+	}
+	for _, test := range tests {
+		//一次只读一个字节，模拟回复被拆分到多次Read中
+		var reader = bufio.NewReader(iotest.OneByteReader(strings.NewReader(test.input)))
+		reply, err := readReply(reader)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: readReply() error = %v, wantErr %v", test.name, err, test.wantErr)
+			continue
+		}
+		if test.wantErr {
+			continue
+		}
+		if reply.Code != test.code || !reflect.DeepEqual(reply.Lines, test.lines) {
+			t.Errorf("%s: readReply() = %d %q, want %d %q", test.name, reply.Code, reply.Lines, test.code, test.lines)
+		}
+	}
+}
+
+func TestReadReplySequence(t *testing.T) {
+	//一次Read可能读到多条回复，每次只取出一条
+	var reader = bufio.NewReader(strings.NewReader("150 opening\r\n226-done\r\n 1 file\r\n226 ok\r\n221 bye\r\n"))
+	var want = []struct {
+		code  int
+		lines int
+	}{{150, 1}, {226, 3}, {221, 1}}
+	for _, w := range want {
+		reply, err := readReply(reader)
+		if err != nil {
+			t.Fatalf("readReply() error = %v", err)
+		}
+		if reply.Code != w.code || len(reply.Lines) != w.lines {
+			t.Errorf("readReply() = %d with %d lines, want %d with %d lines", reply.Code, len(reply.Lines), w.code, w.lines)
+		}
+	}
+	if _, err := readReply(reader); err != io.EOF {
+		t.Errorf("readReply() at end error = %v, want io.EOF", err)
+	}
+}
+
+func TestReplyMessage(t *testing.T) {
+	var tests = []struct {
+		lines []string
+		want  string
+	}{
+		{[]string{"257 \"/home\" is cwd"}, "\"/home\" is cwd"},
+		{[]string{"200"}, "200"},
+		{[]string{"211-Features:", " EPSV", "211 End"}, "Features:\n EPSV\nEnd"},
+		{[]string{"150-first", "226 middle", "150 last"}, "first\n226 middle\nlast"},
+	}
+	for _, test := range tests {
+		if got := (Reply{Lines: test.lines}).Message(); got != test.want {
+			t.Errorf("Reply%q.Message() = %q, want %q", test.lines, got, test.want)
+		}
+	}
+}
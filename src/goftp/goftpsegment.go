@@ -0,0 +1,225 @@
+package goftp
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync"
+)
+
+//分段下载时每一段的最小长度，文件太小的时候会减少分段的数量
+const SEGMENT_MIN_SIZE int64 = 1 << 20
+
+//建立一个新的控制连接，使用相同的选项和用户登录并进入相同的远程目录，
+//传输类型、被动模式、数据连接保护级别和字符集也和当前连接相同。新的
+//连接和当前连接可以同时传输文件，服务器的回复不会写到ReplyLog中
+func (this *Client) Clone() (client *Client, err error) {
+	dir, err := this.Pwd()
+	if err != nil {
+		return
+	}
+	return this.dialClone(dir)
+}
+
+//建立新的控制连接并进入远程目录dir，这个方法不使用当前的控制连接，
+//可以在多个goroutine中同时调用
+func (this *Client) dialClone(dir string) (client *Client, err error) {
+	var opts = this.opts
+	opts.ReplyLog = nil
+	opts.Charset = this.charset
+	opts.ExplicitTLS = this.tlsConfig != nil && !opts.ImplicitTLS
+	if this.conn == nil {
+		return nil, ErrNotConnected
+	}
+	_, port, err := net.SplitHostPort(this.conn.RemoteAddr().String())
+	if err != nil {
+		return
+	}
+	client, err = Dial(net.JoinHostPort(this.host, port), &opts)
+	if err != nil {
+		return
+	}
+	err = client.Login(this.username, this.password)
+	if err == nil && this.account != "" {
+		err = client.Account(this.account)
+	}
+	if err == nil && dir != "" {
+		err = client.Cwd(dir)
+	}
+	if err == nil && client.tlsConfig != nil {
+		err = client.Prot(this.ProtectionLevel())
+	}
+	if err != nil {
+		client.Close()
+		client = nil
+		return
+	}
+	client.transferType = this.transferType
+	client.passive = this.passive
+	client.useEPSV = this.useEPSV
+	return
+}
+
+//分段下载远程文件remotePath并保存为本地文件localPath。除了当前的控制
+//连接，再建立segments-1个控制连接，每个连接用REST和RETR下载文件中不重叠
+//的一段，用WriteAt写到预先分配好大小的本地文件中，下载完自己的一段之后
+//用ABOR结束传输，最后一段由当前连接下载到文件末尾。服务器限制了连接数的
+//时候只使用成功建立的连接，下载完成后检查文件大小
+func (this *Client) downloadSegmented(remotePath string, localPath string, options TransferOptions) (n int64, err error) {
+	if options.Resume || options.Offset > 0 {
+		return 0, errors.New("Segmented download can't be restarted")
+	}
+	if this.transferType == TRANSFER_TYPE_ASCII {
+		return 0, errors.New("Can't download in segments in ASCII mode")
+	}
+	size, err := this.remoteSize(remotePath)
+	if err != nil {
+		return
+	}
+	if size < 0 {
+		return 0, errors.New("Server does not support SIZE, can't download in segments")
+	}
+	var segments = options.Segments
+	if maxSegments := int(size / SEGMENT_MIN_SIZE); segments > maxSegments {
+		segments = maxSegments
+	}
+	var workers []*Client
+	if segments > 1 {
+		if workers, err = this.dialWorkers(segments - 1); err != nil {
+			return
+		}
+	}
+	if len(workers) == 0 {
+		options.Segments = 0
+		return this.DownloadFile(remotePath, localPath, &options)
+	}
+
+	//和DownloadFile一样先下载到临时文件，失败的时候已经存在的本地文件不会被改动
+	localFile, tmpPath, err := createTempFile(localPath)
+	if err == nil {
+		//预先分配整个文件，每一段写到各自的位置
+		if err = localFile.Truncate(size); err != nil {
+			localFile.Close()
+			os.Remove(tmpPath)
+		}
+	}
+	if err != nil {
+		for _, worker := range workers {
+			worker.Quit()
+		}
+		return
+	}
+
+	var tracker = newProgressTracker(0, size, options.Progress)
+	var segmentSize = size / int64(len(workers)+1)
+	var counts = make([]int64, len(workers)+1)
+	var errs = make([]error, len(workers)+1)
+	var wg sync.WaitGroup
+	for i, worker := range workers {
+		wg.Add(1)
+		go func(i int, worker *Client) {
+			defer wg.Done()
+			//ABOR之后连接上可能还有没有读取的回复，这个连接不再使用
+			defer worker.Quit()
+			counts[i], errs[i] = worker.retrSegment(remotePath, localFile, int64(i)*segmentSize, segmentSize, tracker)
+		}(i, worker)
+	}
+	var last = len(workers)
+	var lastOffset = int64(last) * segmentSize
+	counts[last], errs[last] = this.RetrFrom(remotePath, tracker.writer(io.NewOffsetWriter(localFile, lastOffset)), lastOffset)
+	wg.Wait()
+
+	for i := range counts {
+		n += counts[i]
+		if err == nil {
+			err = errs[i]
+		}
+	}
+	if closeErr := localFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		var info os.FileInfo
+		if info, err = os.Stat(tmpPath); err == nil {
+			err = checkTransferSize(info.Size(), size)
+		}
+	}
+	if err == nil && options.VerifyHash {
+		err = this.VerifyHash(remotePath, tmpPath)
+	}
+	var tmpFile = &lazyFile{path: localPath, tmpPath: tmpPath}
+	if err != nil {
+		tmpFile.discard(options.KeepPartial)
+		return
+	}
+	if err = tmpFile.commit(); err != nil {
+		return
+	}
+	if options.PreserveModTime {
+		err = this.preserveLocalModTime(remotePath, localPath)
+	}
+	return
+}
+
+//同时建立count个新的控制连接，返回成功建立的连接，全部失败的时候不是错误
+func (this *Client) dialWorkers(count int) (workers []*Client, err error) {
+	dir, err := this.Pwd()
+	if err != nil {
+		return
+	}
+	var clients = make([]*Client, count)
+	var wg sync.WaitGroup
+	for i := range clients {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			clients[i], _ = this.dialClone(dir)
+		}(i)
+	}
+	wg.Wait()
+	for _, client := range clients {
+		if client != nil {
+			workers = append(workers, client)
+		}
+	}
+	return
+}
+
+//从远程文件的offset处下载length个字节，写到file中相同的位置，下载完之后
+//用ABOR结束传输，tracker不为nil的时候报告进度
+func (this *Client) retrSegment(path string, file io.WriterAt, offset int64, length int64, tracker *progressTracker) (n int64, err error) {
+	if err = this.syncType(); err != nil {
+		return
+	}
+	dataConn, _, err := this.transferAt(offset, FC_RETR, path)
+	if err != nil {
+		return
+	}
+	n, err = io.CopyN(tracker.writer(io.NewOffsetWriter(file, offset)), dataConn, length)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if abortErr := this.abortTransfer(dataConn); err == nil {
+		err = abortErr
+	}
+	return
+}
+
+//用ABOR结束正在进行的传输。先关闭数据连接，服务器发送数据失败后回复426，
+//然后对ABOR回复226；如果传输已经完成，服务器会先回复226，ABOR的回复会
+//留在连接上，所以ABOR之后这个连接最好只用来发送QUIT
+func (this *Client) abortTransfer(dataConn net.Conn) (err error) {
+	dataConn.Close()
+	if err = this.sendCmd(FC_ABOR); err != nil {
+		return
+	}
+	reply, err := this.readReply()
+	if err == nil && reply.IsTransientNegative() {
+		reply, err = this.readReply()
+	}
+	if err == nil && !reply.IsPositiveCompletion() {
+		err = newProtocolError([]string{FC_ABOR}, reply)
+	}
+	return
+}
@@ -0,0 +1,167 @@
+package goftp
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"time"
+)
+
+//数据连接的保护级别，定义见RFC 4217
+type ProtectionLevel string
+
+//上传结束后等待服务器关闭加密的数据连接的时间
+const DATA_CONN_CLOSE_TIMEOUT = 10 * time.Second
+
+const (
+	PROT_LEVEL_CLEAR   ProtectionLevel = "C" //数据连接不加密
+	PROT_LEVEL_PRIVATE ProtectionLevel = "P" //数据连接使用TLS加密
+)
+
+//通过AUTH TLS把控制连接升级为TLS连接，也就是显式FTPS，定义见RFC 4217。
+//升级成功后数据连接默认也使用TLS加密，PBSZ和PROT命令会在下一次传输
+//之前发送
+func (this *Client) AuthTLS() (err error) {
+	if this.tlsConfig != nil {
+		return errors.New("Already using TLS")
+	}
+	if _, err = this.cmdOK(FC_AUTH, "TLS"); err != nil {
+		return
+	}
+	return this.startTLS()
+}
+
+//在控制连接上进行TLS握手，握手成功后控制连接上的数据都通过TLS传输，
+//握手失败的时候会关闭控制连接
+func (this *Client) startTLS() (err error) {
+	tlsConfig, err := this.newTLSConfig()
+	if err != nil {
+		this.Close()
+		return
+	}
+	var tlsConn = this.tlsClient(this.conn, tlsConfig)
+	tlsConn.SetDeadline(time.Now().Add(this.timeout))
+	if err = tlsConn.Handshake(); err != nil {
+		//握手失败之后控制连接已经没法继续使用了
+		this.Close()
+		return
+	}
+	tlsConn.SetDeadline(time.Time{})
+	this.conn = tlsConn
+	this.reader = bufio.NewReader(tlsConn)
+	this.tlsConfig = tlsConfig
+	this.protLevel = PROT_LEVEL_PRIVATE
+	return
+}
+
+//根据DialOptions中的TLSConfig生成控制连接和数据连接共用的配置。很多
+//服务器(比如vsftpd和FileZilla Server)要求数据连接复用控制连接的TLS会话，
+//Go的TLS会话缓存是按照ServerName来查找的，所以这里要设置好ServerName和
+//会话缓存，让数据连接可以找到控制连接的会话
+func (this *Client) newTLSConfig() (tlsConfig *tls.Config, err error) {
+	if this.opts.TLSConfig != nil {
+		tlsConfig = this.opts.TLSConfig.Clone()
+	} else {
+		tlsConfig = &tls.Config{}
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = this.host
+	}
+	if tlsConfig.ClientSessionCache == nil {
+		tlsConfig.ClientSessionCache = tls.NewLRUClientSessionCache(0)
+	}
+	err = this.loadTLSFiles(tlsConfig)
+	return
+}
+
+//在conn上建立TLS客户端连接。设置了KnownHosts的时候每个连接使用tlsConfig
+//的副本，验证证书时操作的是正在握手的连接，副本和tlsConfig共用会话缓存
+func (this *Client) tlsClient(conn net.Conn, tlsConfig *tls.Config) *tls.Conn {
+	if this.opts.KnownHosts != nil {
+		tlsConfig = tlsConfig.Clone()
+		this.pinServerCert(tlsConfig, conn)
+	}
+	return tls.Client(conn, tlsConfig)
+}
+
+//控制连接是否使用了TLS
+func (this *Client) IsTLS() bool {
+	return this.tlsConfig != nil
+}
+
+//设置数据连接的保护级别，PROT命令会在下一次传输之前发送
+func (this *Client) Prot(protLevel ProtectionLevel) (err error) {
+	if this.tlsConfig == nil {
+		return errors.New("Not using TLS, use AUTH TLS first")
+	}
+	switch protLevel {
+	case PROT_LEVEL_CLEAR, PROT_LEVEL_PRIVATE:
+		this.protLevel = protLevel
+	default:
+		err = errors.New("Unknown protection level `" + string(protLevel) + "'")
+	}
+	return
+}
+
+//当前的数据连接保护级别
+func (this *Client) ProtectionLevel() ProtectionLevel {
+	if this.tlsConfig == nil {
+		return PROT_LEVEL_CLEAR
+	}
+	return this.protLevel
+}
+
+//如果服务器当前的数据连接保护级别和设置的不同，发送PBSZ和PROT命令，
+//PROT命令之前必须先发送过PBSZ命令，TLS不需要缓冲区，所以使用PBSZ 0
+func (this *Client) syncProt() (err error) {
+	if this.tlsConfig == nil || this.serverProtLevel == this.protLevel {
+		return
+	}
+	if !this.pbszSent {
+		if _, err = this.cmdOK(FC_PBSZ, "0"); err != nil {
+			return
+		}
+		this.pbszSent = true
+	}
+	if _, err = this.cmdOK(FC_PROT, string(this.protLevel)); err == nil {
+		this.serverProtLevel = this.protLevel
+	}
+	return
+}
+
+//数据连接需要加密的时候进行TLS握手，主动模式下虽然是服务器连接过来的，
+//但是TLS握手时客户端仍然是客户端
+func (this *Client) wrapDataConn(dataConn net.Conn) (tlsConn net.Conn, err error) {
+	if this.tlsConfig == nil || this.serverProtLevel != PROT_LEVEL_PRIVATE {
+		return dataConn, nil
+	}
+	var conn = this.tlsClient(dataConn, this.tlsConfig)
+	conn.SetDeadline(time.Now().Add(this.timeout))
+	if err = conn.Handshake(); err != nil {
+		return
+	}
+	conn.SetDeadline(time.Time{})
+	tlsConn = conn
+	return
+}
+
+//上传结束的时候正常关闭加密的数据连接。TLS 1.3的服务器会在握手之后发送
+//会话票据，客户端一直没有读取，直接关闭连接的话操作系统会发送RST，服务器
+//可能因此丢掉还没有读取的数据，所以要先发送close_notify并关闭写方向，
+//等服务器关闭连接之后再关闭
+func closeUploadConn(dataConn net.Conn) {
+	tlsConn, ok := dataConn.(*tls.Conn)
+	if !ok {
+		return
+	}
+	if tlsConn.CloseWrite() != nil {
+		return
+	}
+	if tcpConn, ok := tlsConn.NetConn().(*net.TCPConn); ok {
+		tcpConn.CloseWrite()
+	}
+	tlsConn.SetReadDeadline(time.Now().Add(DATA_CONN_CLOSE_TIMEOUT))
+	io.Copy(io.Discard, tlsConn)
+}
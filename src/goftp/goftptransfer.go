@@ -0,0 +1,517 @@
+package goftp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//文件传输的类型
+type TransferType string
+
+const (
+	TRANSFER_TYPE_ASCII  TransferType = "A"   //文本模式，换行在网络上传输时使用CRLF
+	TRANSFER_TYPE_BINARY TransferType = "I"   //二进制模式，数据原样传输
+	TRANSFER_TYPE_LOCAL8 TransferType = "L 8" //字节长度为8位的本地模式，和二进制模式一样原样传输
+)
+
+//下载和上传文件时的选项
+type TransferOptions struct {
+	KeepPartial bool  //传输失败的时候保留已经写入的本地文件，断点续传时需要
+	Resume      bool  //比较本地和远程文件的大小，从断点处继续传输，传输结束后检查文件大小
+	Offset      int64 //从指定的位置开始传输，Resume为true时不使用
+
+	Segments   int  //下载时使用的连接数，大于1的时候分段并行下载，不能和Resume、Offset一起使用
+	VerifyHash bool //下载完成后用HASH命令比较远程文件和本地文件的哈希值，服务器不支持HASH时返回错误
+
+	PreserveModTime bool         //传输完成后把目标文件的修改时间设置为源文件的修改时间，失败的时候保留传输的文件
+	Progress        ProgressFunc //报告传输进度，可以为nil，下载时的总字节数用SIZE获取
+}
+
+//文本模式下传输的字节数和文件的大小不一致，没法从断点处继续传输
+var ErrResumeInASCII = errors.New("Can't restart transfer in ASCII mode")
+
+//设置文件传输的类型，TYPE命令不会马上发送，而是等到下一次传输之前，
+//并且只有在和服务器当前的类型不同的时候才发送
+func (this *Client) Type(transferType TransferType) (err error) {
+	switch transferType {
+	case TRANSFER_TYPE_ASCII, TRANSFER_TYPE_BINARY, TRANSFER_TYPE_LOCAL8:
+		this.transferType = transferType
+	default:
+		err = fmt.Errorf("Unknown transfer type `%s'", transferType)
+	}
+	return
+}
+
+//当前的文件传输类型
+func (this *Client) TransferType() TransferType {
+	return this.transferType
+}
+
+//如果服务器当前的传输类型和设置的不同，发送TYPE命令
+func (this *Client) syncType() (err error) {
+	if this.serverType == this.transferType {
+		return
+	}
+	var ftpParams = append([]string{FC_TYPE}, strings.Fields(string(this.transferType))...)
+	if _, err = this.cmdOK(ftpParams...); err == nil {
+		this.serverType = this.transferType
+	}
+	return
+}
+
+//下载远程文件，把文件内容写到writer中，返回从网络上接收的字节数。
+//文本模式下网络上的CRLF会被转换为本地的换行
+func (this *Client) Retr(path string, writer io.Writer) (n int64, err error) {
+	return this.RetrFrom(path, writer, 0)
+}
+
+//从远程文件的offset处开始下载，offset大于0的时候先发送REST命令，
+//文本模式下不能使用
+func (this *Client) RetrFrom(path string, writer io.Writer, offset int64) (n int64, err error) {
+	if offset > 0 && this.transferType == TRANSFER_TYPE_ASCII {
+		return 0, ErrResumeInASCII
+	}
+	if err = this.syncType(); err != nil {
+		return
+	}
+	var ftpParams = []string{FC_RETR, path}
+	dataConn, _, err := this.transferAt(offset, ftpParams...)
+	if err != nil {
+		return
+	}
+	if this.transferType == TRANSFER_TYPE_ASCII {
+		var asciiWriter = newASCIIWriter(writer, LOCAL_NEWLINE)
+		n, err = io.Copy(asciiWriter, dataConn)
+		if err == nil {
+			err = asciiWriter.Flush()
+		}
+	} else {
+		n, err = io.Copy(writer, dataConn)
+	}
+	_, err = this.finishTransfer(dataConn, ftpParams, err)
+	return
+}
+
+//把reader中的内容上传为远程文件path，返回发送的字节数和服务器在传输
+//结束时的回复，服务器空间不足(552)或者文件名不允许(553)的时候返回的
+//*ProtocolError可以用errors.Is和ErrExceededStorage、ErrFileNameNotAllowed比较
+func (this *Client) Stor(path string, reader io.Reader) (n int64, reply Reply, err error) {
+	return this.store(0, reader, FC_STOR, path)
+}
+
+//把reader中的内容写到远程文件path的offset处，offset大于0的时候先发送
+//REST命令，reader应该已经定位到本地文件中对应的位置，文本模式下不能使用
+func (this *Client) StorFrom(path string, reader io.Reader, offset int64) (n int64, reply Reply, err error) {
+	if offset > 0 && this.transferType == TRANSFER_TYPE_ASCII {
+		err = ErrResumeInASCII
+		return
+	}
+	return this.store(offset, reader, FC_STOR, path)
+}
+
+//把reader中的内容追加到远程文件path的末尾，远程文件不存在时会创建
+func (this *Client) Appe(path string, reader io.Reader) (n int64, reply Reply, err error) {
+	return this.store(0, reader, FC_APPE, path)
+}
+
+//把reader中的内容上传为一个由服务器命名的唯一文件，返回服务器使用的文件名，
+//服务器没有在回复中给出文件名的时候name为空
+func (this *Client) Stou(reader io.Reader) (name string, n int64, reply Reply, err error) {
+	var ftpParams = []string{FC_STOU}
+	if err = this.syncType(); err != nil {
+		return
+	}
+	dataConn, preReply, err := this.transfer(ftpParams...)
+	if err != nil {
+		return
+	}
+	n, err = io.Copy(dataConn, this.uploadReader(reader))
+	if err == nil {
+		closeUploadConn(dataConn)
+	}
+	reply, err = this.finishTransfer(dataConn, ftpParams, err)
+	//文件名可能在150回复中，比如`150 FILE: name`，也可能在226回复中，
+	//比如`226 Transfer complete (unique file name:name).`
+	name = parseStouName(preReply)
+	if name == "" {
+		name = parseStouName(reply)
+	}
+	return
+}
+
+//执行STOR或者APPE命令，通过数据连接发送reader中的内容
+func (this *Client) store(offset int64, reader io.Reader, ftpParams ...string) (n int64, reply Reply, err error) {
+	if err = this.syncType(); err != nil {
+		return
+	}
+	dataConn, _, err := this.transferAt(offset, ftpParams...)
+	if err != nil {
+		return
+	}
+	n, err = io.Copy(dataConn, this.uploadReader(reader))
+	if err == nil {
+		closeUploadConn(dataConn)
+	}
+	reply, err = this.finishTransfer(dataConn, ftpParams, err)
+	return
+}
+
+//文本模式下上传的内容需要把本地的换行转换为CRLF
+func (this *Client) uploadReader(reader io.Reader) io.Reader {
+	if this.transferType == TRANSFER_TYPE_ASCII {
+		return newASCIIReader(reader)
+	}
+	return reader
+}
+
+//从STOU命令的回复中找出服务器使用的文件名
+func parseStouName(reply Reply) (name string) {
+	var msg = reply.Message()
+	if index := strings.Index(msg, "FILE:"); index != -1 {
+		return strings.TrimSpace(msg[index+len("FILE:"):])
+	}
+	if index := strings.Index(msg, "unique file name:"); index != -1 {
+		name = msg[index+len("unique file name:"):]
+		name = strings.TrimSpace(strings.TrimRight(strings.TrimSpace(name), ")."))
+	}
+	return
+}
+
+//获取远程文件的大小，使用RFC 3659的SIZE命令，结果和当前的传输类型有关
+func (this *Client) Size(path string) (size int64, err error) {
+	if err = this.syncType(); err != nil {
+		return
+	}
+	reply, err := this.cmdOK(FC_SIZE, path)
+	if err != nil {
+		return
+	}
+	size, err = strconv.ParseInt(strings.TrimSpace(reply.Message()), 10, 64)
+	if err != nil {
+		err = newProtocolError([]string{FC_SIZE, path}, reply)
+	}
+	return
+}
+
+//获取远程文件的最后修改时间，使用RFC 3659的MDTM命令，回复中的时间是
+//YYYYMMDDHHMMSS[.sss]格式的UTC时间
+func (this *Client) ModTime(path string) (modTime time.Time, err error) {
+	reply, err := this.cmdOK(FC_MDTM, path)
+	if err != nil {
+		return
+	}
+	modTime, err = parseMlsxTime(strings.TrimSpace(reply.Message()))
+	if err != nil {
+		err = newProtocolError([]string{FC_MDTM, path}, reply)
+	}
+	return
+}
+
+//断点续传时获取远程文件的大小，服务器不支持SIZE的时候返回-1
+func (this *Client) remoteSize(path string) (size int64, err error) {
+	if features, _ := this.loadFeatures(); len(features) > 0 && !features.Has(FEAT_SIZE) {
+		return -1, nil
+	}
+	size, err = this.Size(path)
+	if errors.Is(err, ErrSyntax) || errors.Is(err, ErrNotImplemented) {
+		return -1, nil
+	}
+	return
+}
+
+//检查传输结束后的文件大小
+func checkTransferSize(localSize int64, remoteSize int64) (err error) {
+	if localSize != remoteSize {
+		err = fmt.Errorf("Size mismatch after transfer: local %d bytes, remote %d bytes", localSize, remoteSize)
+	}
+	return
+}
+
+//设置远程文件的最后修改时间，服务器支持MFMT的时候使用MFMT，否则使用一些
+//服务器支持的`MDTM YYYYMMDDHHMMSS path`形式，发送的时间是UTC时间，不包括
+//秒以下的部分
+func (this *Client) SetModTime(path string, modTime time.Time) (err error) {
+	var value = modTime.UTC().Format(MLSX_TIME_LAYOUT)
+	if features, _ := this.loadFeatures(); features.Has(FEAT_MFMT) {
+		_, err = this.cmdOK(FC_MFMT, value, path)
+		return
+	}
+	_, err = this.cmdOK(FC_MDTM, value, path)
+	return
+}
+
+//把本地文件的修改时间设置为远程文件的修改时间
+func (this *Client) preserveLocalModTime(remotePath string, localPath string) (err error) {
+	modTime, err := this.ModTime(remotePath)
+	if err == nil {
+		err = os.Chtimes(localPath, modTime, modTime)
+	}
+	if err != nil {
+		err = fmt.Errorf("Can't preserve modification time: %w", err)
+	}
+	return
+}
+
+//把本地文件localPath上传为远程文件remotePath，文件内容直接从磁盘读取并
+//发送，不会一次全部读入内存。断点续传的时候，服务器支持REST STREAM就用
+//REST加STOR，否则用APPE追加到远程文件的末尾
+func (this *Client) UploadFile(localPath string, remotePath string, opts *TransferOptions) (n int64, reply Reply, err error) {
+	var options TransferOptions
+	if opts != nil {
+		options = *opts
+	}
+	if (options.Resume || options.Offset > 0) && this.transferType == TRANSFER_TYPE_ASCII {
+		err = ErrResumeInASCII
+		return
+	}
+	localFile, err := os.Open(localPath)
+	if err != nil {
+		return
+	}
+	defer localFile.Close()
+	info, err := localFile.Stat()
+	if err != nil {
+		return
+	}
+
+	var offset = options.Offset
+	if options.Resume {
+		remoteSize, sizeErr := this.remoteSize(remotePath)
+		if errors.Is(sizeErr, ErrFileUnavailable) {
+			//远程文件还不存在，从头开始上传
+			remoteSize, sizeErr = 0, nil
+		}
+		if sizeErr != nil {
+			err = sizeErr
+			return
+		}
+		if remoteSize < 0 {
+			err = errors.New("Server does not support SIZE, can't resume upload")
+			return
+		}
+		if remoteSize > info.Size() {
+			err = fmt.Errorf("Remote file `%s' is larger than local file", remotePath)
+			return
+		}
+		if remoteSize == info.Size() {
+			return
+		}
+		offset = remoteSize
+	}
+	if offset > 0 {
+		if _, err = localFile.Seek(offset, io.SeekStart); err != nil {
+			return
+		}
+	}
+
+	var reader = newProgressTracker(offset, info.Size(), options.Progress).reader(localFile)
+	if offset > 0 && options.Resume && !this.HasRestStream() {
+		n, reply, err = this.Appe(remotePath, reader)
+	} else {
+		n, reply, err = this.StorFrom(remotePath, reader, offset)
+	}
+	if err == nil && options.Resume {
+		remoteSize, sizeErr := this.remoteSize(remotePath)
+		if sizeErr != nil {
+			err = sizeErr
+		} else if remoteSize >= 0 {
+			err = checkTransferSize(info.Size(), remoteSize)
+		}
+	}
+	if err == nil && options.PreserveModTime {
+		if err = this.SetModTime(remotePath, info.ModTime()); err != nil {
+			err = fmt.Errorf("Can't preserve modification time: %w", err)
+		}
+	}
+	return
+}
+
+//服务器是否支持REST STREAM，不支持FEAT的服务器当作支持
+func (this *Client) HasRestStream() bool {
+	features, err := this.loadFeatures()
+	return err == nil && (len(features) == 0 || features.RestStream())
+}
+
+//下载远程文件remotePath并保存为本地文件localPath。文件先下载到同一个目录
+//中的临时文件，下载成功之后再替换本地文件，下载失败的时候删除临时文件，
+//已经存在的本地文件不会被改动，设置了opts.KeepPartial的时候用下载了一部分
+//的文件替换本地文件。断点续传或者指定了opts.Offset的时候，直接以追加的方式
+//写到本地文件中，从本地文件的大小处开始下载，已经下载完整的文件不会重新
+//下载，断点续传的文件下载失败的时候不会删除
+func (this *Client) DownloadFile(remotePath string, localPath string, opts *TransferOptions) (n int64, err error) {
+	var options TransferOptions
+	if opts != nil {
+		options = *opts
+	}
+	if options.Segments > 1 {
+		return this.downloadSegmented(remotePath, localPath, options)
+	}
+	if (options.Resume || options.Offset > 0) && this.transferType == TRANSFER_TYPE_ASCII {
+		return 0, ErrResumeInASCII
+	}
+
+	var offset = options.Offset
+	var remoteSize int64 = -1
+	if options.Resume {
+		if remoteSize, err = this.remoteSize(remotePath); err != nil {
+			return
+		}
+		if info, statErr := os.Stat(localPath); statErr == nil {
+			offset = info.Size()
+		}
+		if remoteSize >= 0 && offset > remoteSize {
+			err = fmt.Errorf("Local file `%s' is larger than remote file", localPath)
+			return
+		}
+		if remoteSize >= 0 && offset == remoteSize {
+			return
+		}
+	}
+
+	var localFile = &lazyFile{path: localPath, offset: offset, inPlace: options.Resume || offset > 0}
+	var total = remoteSize
+	if options.Progress != nil && !options.Resume {
+		if total, err = this.remoteSize(remotePath); err != nil {
+			//文件不存在之类的错误由RETR报告
+			total, err = -1, nil
+		}
+	}
+	var writer = newProgressTracker(offset, total, options.Progress).writer(localFile)
+	n, err = this.RetrFrom(remotePath, writer, offset)
+	if err == nil {
+		//空文件没有数据，传输成功之后再创建
+		err = localFile.open()
+	}
+	if closeErr := localFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && remoteSize >= 0 {
+		var info os.FileInfo
+		if info, err = os.Stat(localFile.name()); err == nil {
+			err = checkTransferSize(info.Size(), remoteSize)
+		}
+	}
+	//哈希值不一致的文件和下载失败一样处理
+	if err == nil && options.VerifyHash {
+		err = this.VerifyHash(remotePath, localFile.name())
+	}
+	if err != nil {
+		localFile.discard(options.KeepPartial || options.Resume)
+		return
+	}
+	if err = localFile.commit(); err != nil {
+		return
+	}
+	if options.PreserveModTime {
+		err = this.preserveLocalModTime(remotePath, localPath)
+	}
+	return
+}
+
+//下载时的本地文件，第一次写入的时候才打开。inPlace为true的时候直接写到
+//本地文件中，offset大于0的时候以追加的方式打开，offset之后的内容会被丢弃，
+//否则写到临时文件中，下载成功之后用commit替换本地文件
+type lazyFile struct {
+	path    string
+	offset  int64
+	inPlace bool
+	file    *os.File
+	tmpPath string //临时文件的路径，inPlace为true的时候为空
+	created bool   //inPlace为true的时候，本地文件是不是这一次打开的时候创建的
+}
+
+func (this *lazyFile) open() (err error) {
+	if this.file != nil {
+		return
+	}
+	if !this.inPlace {
+		this.file, this.tmpPath, err = createTempFile(this.path)
+		return
+	}
+	_, statErr := os.Stat(this.path)
+	var created = os.IsNotExist(statErr)
+	var file *os.File
+	if this.offset > 0 {
+		if file, err = os.OpenFile(this.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666); err != nil {
+			return
+		}
+		if err = file.Truncate(this.offset); err != nil {
+			file.Close()
+			return
+		}
+	} else if file, err = os.Create(this.path); err != nil {
+		return
+	}
+	this.file, this.created = file, created
+	return
+}
+
+func (this *lazyFile) Write(p []byte) (n int, err error) {
+	if err = this.open(); err != nil {
+		return
+	}
+	return this.file.Write(p)
+}
+
+func (this *lazyFile) Close() (err error) {
+	if this.file == nil {
+		return
+	}
+	return this.file.Close()
+}
+
+//数据实际写到的文件
+func (this *lazyFile) name() string {
+	if this.tmpPath != "" {
+		return this.tmpPath
+	}
+	return this.path
+}
+
+//下载成功之后用临时文件替换本地文件
+func (this *lazyFile) commit() (err error) {
+	if this.tmpPath == "" {
+		return
+	}
+	if err = os.Rename(this.tmpPath, this.path); err != nil {
+		os.Remove(this.tmpPath)
+	}
+	return
+}
+
+//下载失败之后删除临时文件或者这一次创建的本地文件，keep为true的时候保留
+//已经下载的部分
+func (this *lazyFile) discard(keep bool) {
+	switch {
+	case this.tmpPath != "" && keep:
+		os.Rename(this.tmpPath, this.path)
+	case this.tmpPath != "":
+		os.Remove(this.tmpPath)
+	case this.created && !keep:
+		os.Remove(this.path)
+	}
+}
+
+//在path所在的目录中创建下载用的临时文件，权限和os.Create创建的文件一样，
+//path已经存在的时候使用path的权限
+func createTempFile(path string) (file *os.File, tmpPath string, err error) {
+	for i := 0; ; i++ {
+		tmpPath = fmt.Sprintf("%s.%d-%d.tmp", path, os.Getpid(), i)
+		file, err = os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+		if !os.IsExist(err) {
+			break
+		}
+	}
+	if err != nil {
+		return
+	}
+	if info, statErr := os.Stat(path); statErr == nil {
+		file.Chmod(info.Mode().Perm())
+	}
+	return
+}
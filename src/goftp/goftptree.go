@@ -0,0 +1,223 @@
+package goftp
+
+import (
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+//递归传输时最多进入的目录层数，跟随符号链接的时候防止循环
+const TREE_MAX_DEPTH = 64
+
+var (
+	ErrSymlinkSkipped = errors.New("symbolic link skipped")
+	ErrNotPlainFile   = errors.New("not a plain file")
+	ErrTooDeep        = errors.New("too many levels of directories")
+	ErrInvalidName    = errors.New("invalid file name")
+)
+
+//递归传输目录时的选项
+type TreeOptions struct {
+	TransferOptions                         //传输每个文件时使用的选项
+	FollowSymlinks  bool                    //跟随符号链接，默认跳过符号链接
+	Progress        func(result TreeResult) //每处理完一个文件或者目录调用一次，可以为nil
+}
+
+//递归传输中一个文件或者目录的结果
+type TreeResult struct {
+	Path    string //相对于传输的根目录的路径，使用`/`分隔
+	Dir     bool   //是不是目录
+	Size    int64  //传输的字节数
+	Skipped bool   //是否被跳过，跳过的原因在Err中
+	Err     error  //传输失败的原因
+}
+
+//记录递归传输的结果
+type treeWalker struct {
+	options TreeOptions
+	results []TreeResult
+}
+
+func (this *treeWalker) add(result TreeResult) {
+	this.results = append(this.results, result)
+	if this.options.Progress != nil {
+		this.options.Progress(result)
+	}
+}
+
+//跳过一个文件或者目录
+func (this *treeWalker) skip(relPath string, dir bool, reason error) {
+	this.add(TreeResult{Path: relPath, Dir: dir, Skipped: true, Err: reason})
+}
+
+//服务器返回的文件名不能包含路径分隔符，防止写到本地目录之外
+func isPlainName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, "/\\")
+}
+
+//把远程目录remoteDir下的所有文件和子目录下载到本地目录localDir中，保持
+//相对的目录结构，本地目录不存在的时候会创建。使用List获取远程文件列表，
+//服务器支持MLST的时候使用MLSD。一个文件失败的时候继续传输后面的文件，
+//每个文件的结果按照处理的顺序返回，只有远程目录不能列出或者连接断开的
+//时候才返回错误
+func (this *Client) DownloadDir(remoteDir string, localDir string, opts *TreeOptions) (results []TreeResult, err error) {
+	var walker = &treeWalker{}
+	if opts != nil {
+		walker.options = *opts
+	}
+	if err = os.MkdirAll(localDir, 0777); err != nil {
+		return
+	}
+	err = this.downloadDir(walker, remoteDir, localDir, "", 0)
+	return walker.results, err
+}
+
+func (this *Client) downloadDir(walker *treeWalker, remoteDir string, localDir string, relDir string, depth int) (err error) {
+	if depth > TREE_MAX_DEPTH {
+		walker.add(TreeResult{Path: relDir, Dir: true, Err: ErrTooDeep})
+		return
+	}
+	entries, err := this.List(remoteDir)
+	var parseErr *ListParseError
+	if errors.As(err, &parseErr) {
+		//能解析的文件仍然下载
+		walker.add(TreeResult{Path: relDir, Dir: true, Err: err})
+		err = nil
+	}
+	if err != nil {
+		if depth == 0 || isConnectionLost(err) {
+			return
+		}
+		walker.add(TreeResult{Path: relDir, Dir: true, Err: err})
+		return nil
+	}
+
+	for _, entry := range entries {
+		var relPath = path.Join(relDir, entry.Name)
+		if !isPlainName(entry.Name) {
+			walker.add(TreeResult{Path: relPath, Err: ErrInvalidName})
+			continue
+		}
+		var remotePath = path.Join(remoteDir, entry.Name)
+		var localPath = filepath.Join(localDir, entry.Name)
+		var transferErr error
+		switch entry.Type {
+		case ENTRY_TYPE_DIR:
+			err = this.downloadSubdir(walker, remotePath, localPath, relPath, depth)
+		case ENTRY_TYPE_LINK:
+			if !walker.options.FollowSymlinks {
+				walker.skip(relPath, false, ErrSymlinkSkipped)
+				continue
+			}
+			//不知道链接指向的是文件还是目录，先当作文件下载，服务器回复550
+			//的时候再当作目录
+			var n int64
+			n, transferErr = this.DownloadFile(remotePath, localPath, &walker.options.TransferOptions)
+			if errors.Is(transferErr, ErrFileUnavailable) {
+				err = this.downloadSubdir(walker, remotePath, localPath, relPath, depth)
+			} else {
+				walker.add(TreeResult{Path: relPath, Size: n, Err: transferErr})
+			}
+		default:
+			var n int64
+			n, transferErr = this.DownloadFile(remotePath, localPath, &walker.options.TransferOptions)
+			walker.add(TreeResult{Path: relPath, Size: n, Err: transferErr})
+		}
+		if err == nil && isConnectionLost(transferErr) {
+			err = transferErr
+		}
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+//创建本地子目录并下载远程子目录
+func (this *Client) downloadSubdir(walker *treeWalker, remotePath string, localPath string, relPath string, depth int) (err error) {
+	if mkdirErr := os.MkdirAll(localPath, 0777); mkdirErr != nil {
+		walker.add(TreeResult{Path: relPath, Dir: true, Err: mkdirErr})
+		return
+	}
+	walker.add(TreeResult{Path: relPath, Dir: true})
+	return this.downloadDir(walker, remotePath, localPath, relPath, depth+1)
+}
+
+//把本地目录localDir下的所有文件和子目录上传到远程目录remoteDir中，保持
+//相对的目录结构，远程目录用MKD创建。MKD回复550的时候当作目录已经存在，
+//继续上传目录下的文件。一个文件失败的时候继续传输后面的文件，每个文件的
+//结果按照处理的顺序返回，只有本地目录不能读取、远程目录不能创建或者连接
+//断开的时候才返回错误
+func (this *Client) UploadDir(localDir string, remoteDir string, opts *TreeOptions) (results []TreeResult, err error) {
+	var walker = &treeWalker{}
+	if opts != nil {
+		walker.options = *opts
+	}
+	if _, err = this.Mkdir(remoteDir); err != nil && !errors.Is(err, ErrFileUnavailable) {
+		return
+	}
+	err = this.uploadDir(walker, localDir, remoteDir, "", 0)
+	return walker.results, err
+}
+
+func (this *Client) uploadDir(walker *treeWalker, localDir string, remoteDir string, relDir string, depth int) (err error) {
+	if depth > TREE_MAX_DEPTH {
+		walker.add(TreeResult{Path: relDir, Dir: true, Err: ErrTooDeep})
+		return
+	}
+	entries, err := os.ReadDir(localDir)
+	if err != nil {
+		if depth == 0 {
+			return
+		}
+		walker.add(TreeResult{Path: relDir, Dir: true, Err: err})
+		return nil
+	}
+
+	for _, entry := range entries {
+		var localPath = filepath.Join(localDir, entry.Name())
+		var remotePath = path.Join(remoteDir, entry.Name())
+		var relPath = path.Join(relDir, entry.Name())
+		var fileMode = entry.Type()
+		if fileMode&os.ModeSymlink != 0 {
+			if !walker.options.FollowSymlinks {
+				walker.skip(relPath, false, ErrSymlinkSkipped)
+				continue
+			}
+			info, statErr := os.Stat(localPath)
+			if statErr != nil {
+				walker.add(TreeResult{Path: relPath, Err: statErr})
+				continue
+			}
+			fileMode = info.Mode().Type()
+		}
+
+		var transferErr error
+		switch {
+		case fileMode.IsDir():
+			_, transferErr = this.Mkdir(remotePath)
+			if transferErr != nil && !errors.Is(transferErr, ErrFileUnavailable) {
+				walker.add(TreeResult{Path: relPath, Dir: true, Err: transferErr})
+				break
+			}
+			transferErr = nil
+			walker.add(TreeResult{Path: relPath, Dir: true})
+			err = this.uploadDir(walker, localPath, remotePath, relPath, depth+1)
+		case fileMode.IsRegular():
+			var n int64
+			n, _, transferErr = this.UploadFile(localPath, remotePath, &walker.options.TransferOptions)
+			walker.add(TreeResult{Path: relPath, Size: n, Err: transferErr})
+		default:
+			walker.skip(relPath, false, ErrNotPlainFile)
+		}
+		if err == nil && isConnectionLost(transferErr) {
+			err = transferErr
+		}
+		if err != nil {
+			return
+		}
+	}
+	return
+}
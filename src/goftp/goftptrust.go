@@ -0,0 +1,215 @@
+package goftp
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//服务器证书不被信任时返回的错误，可以用errors.Is来判断
+var ErrCertificateRejected = errors.New("server certificate rejected")
+
+//服务器证书没有通过CA验证，需要根据指纹决定是否信任的时候调用，
+//knownFingerprint为空表示第一次连接这个服务器，不为空的时候也可能是
+//以前通过了CA验证的证书，返回true表示信任这个证书，证书的指纹会被记录下来
+type TrustCallback func(hostport string, fingerprint string, knownFingerprint string) bool
+
+//记录服务器证书指纹的文件，和ssh的known_hosts类似，每行一条记录，
+//`#`开头的行是注释
+//
+//	host:port SHA256:AB:CD:...
+type KnownHosts struct {
+	path  string
+	hosts map[string]string
+	mutex sync.Mutex
+}
+
+//从path加载服务器证书指纹，文件不存在的时候返回空的记录，第一次
+//记录指纹的时候会创建文件
+func LoadKnownHosts(path string) (knownHosts *KnownHosts, err error) {
+	knownHosts = &KnownHosts{
+		path:  path,
+		hosts: make(map[string]string),
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		} else {
+			knownHosts = nil
+		}
+		return
+	}
+	defer file.Close()
+
+	var scanner = bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		var line = strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var fields = strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: invalid known host line", path, lineNo)
+		}
+		knownHosts.hosts[fields[0]] = fields[1]
+	}
+	if err = scanner.Err(); err != nil {
+		knownHosts = nil
+	}
+	return
+}
+
+//查找服务器记录的证书指纹
+func (this *KnownHosts) Lookup(hostport string) (fingerprint string, ok bool) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	fingerprint, ok = this.hosts[hostport]
+	return
+}
+
+//记录或者替换服务器的证书指纹并保存到文件中，保存失败的时候指纹
+//仍然会在内存中记录，本次运行中继续有效
+func (this *KnownHosts) Add(hostport string, fingerprint string) (err error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.hosts[hostport] = fingerprint
+	if this.path == "" {
+		return
+	}
+
+	var hostports = make([]string, 0, len(this.hosts))
+	for hostport := range this.hosts {
+		hostports = append(hostports, hostport)
+	}
+	sort.Strings(hostports)
+	var lines []string
+	for _, hostport := range hostports {
+		lines = append(lines, hostport+" "+this.hosts[hostport]+"\n")
+	}
+	//先写到临时文件再改名，避免写到一半的时候留下不完整的文件
+	var tmpPath = this.path + ".tmp"
+	if err = os.WriteFile(tmpPath, []byte(strings.Join(lines, "")), 0600); err != nil {
+		return
+	}
+	if err = os.Rename(tmpPath, this.path); err != nil {
+		os.Remove(tmpPath)
+	}
+	return
+}
+
+//证书的SHA-256指纹，格式和`openssl x509 -fingerprint -sha256`的输出一样
+func CertFingerprint(cert *x509.Certificate) string {
+	var sum = sha256.Sum256(cert.Raw)
+	var hexBytes = make([]string, len(sum))
+	for i, b := range sum {
+		hexBytes[i] = fmt.Sprintf("%02X", b)
+	}
+	return "SHA256:" + strings.Join(hexBytes, ":")
+}
+
+//根据DialOptions中的CAFile、CertFile和KeyFile设置TLS配置
+func (this *Client) loadTLSFiles(tlsConfig *tls.Config) (err error) {
+	if this.opts.CAFile != "" {
+		pemData, readErr := os.ReadFile(this.opts.CAFile)
+		if readErr != nil {
+			return readErr
+		}
+		var certPool = x509.NewCertPool()
+		if !certPool.AppendCertsFromPEM(pemData) {
+			return fmt.Errorf("No certificates found in `%s'", this.opts.CAFile)
+		}
+		tlsConfig.RootCAs = certPool
+	}
+	if this.opts.CertFile != "" {
+		//私钥和证书可以放在同一个文件中
+		var keyFile = this.opts.KeyFile
+		if keyFile == "" {
+			keyFile = this.opts.CertFile
+		}
+		cert, loadErr := tls.LoadX509KeyPair(this.opts.CertFile, keyFile)
+		if loadErr != nil {
+			return loadErr
+		}
+		tlsConfig.Certificates = append(tlsConfig.Certificates, cert)
+	}
+	return
+}
+
+//设置了KnownHosts的时候，先按照正常的方式用CA验证服务器证书，验证
+//不通过再根据记录的指纹决定是否信任，第一次连接时信任并记录服务器的
+//证书，以后证书发生变化时由TrustCallback决定，没有TrustCallback的时候
+//拒绝连接，信任的指纹不能保存到文件中的时候也拒绝连接。通过CA验证的
+//证书也会记录指纹，以后服务器换成没有通过CA验证的证书时同样当作证书
+//发生了变化。tlsConfig只用于conn上的握手，询问用户的时候解除conn的超时
+func (this *Client) pinServerCert(tlsConfig *tls.Config, conn net.Conn) {
+	var knownHosts = this.opts.KnownHosts
+	if knownHosts == nil {
+		return
+	}
+	var port = "0"
+	if addr, ok := this.conn.RemoteAddr().(*net.TCPAddr); ok {
+		port = fmt.Sprint(addr.Port)
+	}
+	var hostport = net.JoinHostPort(this.host, port)
+	var timeout = this.timeout
+	var verifyChain = !tlsConfig.InsecureSkipVerify
+	var trustCallback = this.opts.TrustCallback
+	tlsConfig.InsecureSkipVerify = true
+	tlsConfig.VerifyConnection = func(state tls.ConnectionState) (err error) {
+		if len(state.PeerCertificates) == 0 {
+			return errors.New("Server sent no certificate")
+		}
+		var fingerprint = CertFingerprint(state.PeerCertificates[0])
+		knownFingerprint, _ := knownHosts.Lookup(hostport)
+		if fingerprint == knownFingerprint {
+			return
+		}
+		var trusted = verifyChain && verifyCertChain(tlsConfig, state) == nil
+		if !trusted {
+			if trustCallback != nil {
+				//回调可能要等用户回答，这时候不能受握手的超时时间限制，
+				//握手结束之后调用者会清除超时时间
+				conn.SetDeadline(time.Time{})
+				trusted = trustCallback(hostport, fingerprint, knownFingerprint)
+				conn.SetDeadline(time.Now().Add(timeout))
+			} else {
+				trusted = knownFingerprint == ""
+			}
+		}
+		if !trusted {
+			if knownFingerprint == "" {
+				return fmt.Errorf("%w: %s has unknown fingerprint %s", ErrCertificateRejected, hostport, fingerprint)
+			}
+			return fmt.Errorf("%w: %s fingerprint %s does not match known %s",
+				ErrCertificateRejected, hostport, fingerprint, knownFingerprint)
+		}
+		if err = knownHosts.Add(hostport, fingerprint); err != nil {
+			err = fmt.Errorf("Can't save fingerprint of %s: %w", hostport, err)
+		}
+		return
+	}
+}
+
+//和InsecureSkipVerify为false时TLS握手中做的验证一样
+func verifyCertChain(tlsConfig *tls.Config, state tls.ConnectionState) (err error) {
+	var opts = x509.VerifyOptions{
+		Roots:         tlsConfig.RootCAs,
+		DNSName:       tlsConfig.ServerName,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range state.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err = state.PeerCertificates[0].Verify(opts)
+	return
+}
@@ -1,26 +1,56 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"goftp"
 	"os"
+	"path/filepath"
 	"strconv"
 )
 
-//ftp服务器默认监听端口号
-const (
-	FTP_SERVER_DEFAULT_LISTENING_PORT = 21
+//定义ftp命令的参数
+// -implicit 参数 使用隐式FTPS连接ftp服务器，默认端口为990
+// -cafile 参数 验证服务器证书的CA证书文件，默认使用系统的CA证书
+// -cert 参数 客户端证书文件
+// -key 参数 客户端证书的私钥文件，默认从客户端证书文件中读取
+// -knownhosts 参数 记录服务器证书指纹的文件，默认为~/.goftp_known_hosts
+// -charset 参数 控制连接上文件名使用的字符集，比如gbk，默认不转换
+// -i 选项 mget、mput等多个文件的命令不对每个文件询问
+// -g 选项 不展开文件名中的通配符
+var (
+	implicitFlag   = flag.Bool("implicit", false, "使用隐式FTPS")
+	caFileFlag     = flag.String("cafile", "", "CA证书文件")
+	certFlag       = flag.String("cert", "", "客户端证书文件")
+	keyFlag        = flag.String("key", "", "客户端证书的私钥文件")
+	knownHostsFlag = flag.String("knownhosts", defaultKnownHostsFile(), "服务器证书指纹文件")
+	charsetFlag    = flag.String("charset", "", "文件名使用的字符集")
+	noPromptFlag   = flag.Bool("i", false, "多个文件的命令不询问")
+	noGlobFlag     = flag.Bool("g", false, "不展开通配符")
 )
 
 func help() {
-	fmt.Println("usage: ftp [host-name] [port]")
+	fmt.Println("usage: ftp [-i] [-g] [-implicit] [-cafile file] [-cert file] [-key file] [-knownhosts file] [-charset name]")
+	fmt.Println("           [[ftp://|ftps://]host-name] [port]")
+}
+
+//默认的服务器证书指纹文件
+func defaultKnownHostsFile() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".goftp_known_hosts")
 }
 
 func main() {
+	flag.Usage = help
+	flag.Parse()
+
 	var ftpServerHost string
 	var ftpServerPort int
-	//获取命令行参数切片(不包括命令名称)
-	var progArgs = os.Args[1:]
+	//获取命令行参数切片(不包括命令名称和选项)
+	var progArgs = flag.Args()
 	var progArgCount = len(progArgs)
 	//检查命令行参数
 	/*
@@ -33,29 +63,51 @@ func main() {
 	  3. ftp hostname port
 	     尝试以hostname所指定的主机名，port所指定的ftp服务器监听端口来连接
 	     ftp服务器，连接成功或失败后进入ftp交互式命令界面
+	  4. ftp -implicit hostname [port] 或者 ftp ftps://hostname [port]
+	     使用隐式FTPS连接ftp服务器，默认端口为990
 	*/
 	switch progArgCount {
 	case 0:
 		ftpServerHost = ""
-		ftpServerPort = FTP_SERVER_DEFAULT_LISTENING_PORT
 	case 1:
 		ftpServerHost = progArgs[0]
-		ftpServerPort = FTP_SERVER_DEFAULT_LISTENING_PORT
 	case 2:
 		ftpServerHost = progArgs[0]
 		port, err := strconv.Atoi(progArgs[1])
 		if err != nil {
-			ftpServerPort = port
-		} else {
-			ftpServerPort = FTP_SERVER_DEFAULT_LISTENING_PORT
+			help()
+			os.Exit(1)
 		}
+		ftpServerPort = port
 	default:
 		help()
+		os.Exit(1)
+	}
+
+	var charset goftp.Charset
+	if *charsetFlag != "" {
+		var err error
+		if charset, err = goftp.LookupCharset(*charsetFlag); err != nil {
+			fmt.Println("ftp:", err.Error())
+			os.Exit(1)
+		}
 	}
 
+	//端口号为0时根据是否使用隐式FTPS选择默认端口号
 	var ftpClient = goftp.GoFtpClient{
-		Host: ftpServerHost,
-		Port: ftpServerPort,
+		Host:        ftpServerHost,
+		Port:        ftpServerPort,
+		ImplicitTLS: *implicitFlag,
+
+		CAFile:         *caFileFlag,
+		CertFile:       *certFlag,
+		KeyFile:        *keyFlag,
+		KnownHostsFile: *knownHostsFlag,
+
+		Charset: charset,
+
+		NoGlob:   *noGlobFlag,
+		NoPrompt: *noPromptFlag,
 	}
 	if ftpClient.Host != "" {
 		ftpClient.TryConnect()